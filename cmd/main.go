@@ -4,16 +4,21 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
+	_ "time/tzdata" // Embed the time zone database so task time zones work in minimal containers
 
 	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/holiday"
 	"github.com/somepgs/go_final_project/pkg/server"
 )
 
 // config holds the configuration for the application.
 type config struct {
-	Port     int
-	DBFile   string
-	Password string
+	Port      int
+	DBFile    string
+	Password  string
+	Holidays  string
+	TrashDays int
 }
 
 // envOr retrieves the value of the environment variable named by key.
@@ -30,19 +35,38 @@ func loadConfig() config {
 	if err != nil || port <= 0 || port > 65535 {
 		log.Fatalf("invalid TODO_PORT: %v", err)
 	}
+	trashDays, err := strconv.Atoi(envOr("TODO_TRASH_DAYS", "30")) // Deleted tasks are kept for 30 days by default
+	if err != nil || trashDays < 0 {
+		log.Fatalf("invalid TODO_TRASH_DAYS: %v", err)
+	}
 	return config{
-		Port:     port,
-		DBFile:   envOr("TODO_DBFILE", "scheduler.db"), // Default database file is scheduler.db
-		Password: envOr("TODO_PASSWORD", "12345"),      // Default password is 12345
+		Port:      port,
+		DBFile:    envOr("TODO_DBFILE", "scheduler.db"), // Default database file is scheduler.db
+		Password:  envOr("TODO_PASSWORD", "12345"),      // Default password is 12345
+		Holidays:  envOr("TODO_HOLIDAYS", ""),           // No holiday calendar by default, only weekends are days off
+		TrashDays: trashDays,                            // Deleted tasks stay in the trash for this many days, 0 keeps them
 	}
 }
 
 // main initializes the database and starts the server.
-// It checks for the existence of the database file and creates the scheduler table if it does not exist.
+// The database schema is migrated to the latest version on start.
+// "todo migrate ..." manages the schema version instead of starting the server.
 func main() {
 	cfg := loadConfig()
 
-	err := db.Init(cfg.DBFile) // Initialize the database
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.DBFile, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err := holiday.Init(cfg.Holidays) // Load the holiday calendar
+	if err != nil {
+		log.Fatalf("Failed to load holidays: %v", err)
+	}
+
+	err = db.Init(cfg.DBFile) // Initialize the database
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
@@ -52,5 +76,8 @@ func main() {
 			log.Printf("Error closing database: %v", err)
 		}
 	}()
+	if cfg.TrashDays > 0 {
+		go purgeTrash(time.Duration(cfg.TrashDays) * 24 * time.Hour) // Empty the trash of old tasks in the background
+	}
 	server.Run(cfg.Port, cfg.Password) // Start the server
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const migrateUsage = `usage: todo migrate up [version]   apply the migrations up to the version, the latest by default
+       todo migrate down [version] revert the migrations down to the version, the previous one by default
+       todo migrate status         list the migrations and the ones the database has`
+
+// runMigrate runs the migrate subcommand with its arguments against the database file.
+func runMigrate(dbFile string, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+	if err := db.Open(dbFile); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	current, err := db.Version()
+	if err != nil {
+		return err
+	}
+	target := -1
+	if len(args) == 2 {
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		if target < 0 {
+			target = db.LatestVersion()
+		}
+		if target < current {
+			return fmt.Errorf("the database is at version %d, use down to go back to %d", current, target)
+		}
+	case "down":
+		if target < 0 {
+			target = max(current-1, 0)
+		}
+		if target > current {
+			return fmt.Errorf("the database is at version %d, use up to go forward to %d", current, target)
+		}
+	case "status":
+		if len(args) > 1 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		return printStatus(current)
+	default:
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	if err := db.Migrate(target); err != nil {
+		return err
+	}
+	fmt.Printf("Schema version %d → %d\n", current, target)
+	return nil
+}
+
+// printStatus lists the migrations with the time each one was applied.
+func printStatus(current int) error {
+	list, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, m := range list {
+		applied := m.AppliedAt
+		if applied == "" {
+			applied = "pending"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Schema version %d of %d\n", current, db.LatestVersion())
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const purgeInterval = time.Hour // purgeInterval is how often the trash is checked for tasks past their retention
+
+// purgeTrash removes the tasks that have been in the trash longer than the retention period,
+// on start and then every purgeInterval.
+func purgeTrash(retention time.Duration) {
+	for {
+		purged, err := db.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d tasks from the trash", purged)
+		}
+		time.Sleep(purgeInterval)
+	}
+}
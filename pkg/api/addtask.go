@@ -2,11 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
 )
 
 func addTaskHandler(w http.ResponseWriter, r *http.Request) {
@@ -21,6 +24,14 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := checkDepends(&task); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if err := checkProject(&task, nil); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
 	if err := checkDate(&task); err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 		return
@@ -37,30 +48,121 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func checkDate(task *db.Task) error {
-	now := time.Now()
+	if task.Time != "" {
+		if err := checkTime(task.Time); err != nil {
+			return err
+		}
+	}
+	loc, err := loadLocation(task.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(loc)
 	if task.Date == "" {
 		task.Date = now.Format(formatDate)
 	}
 
-	t, err := time.Parse(formatDate, task.Date)
+	t, err := taskStart(task)
 	if err != nil {
 		return err
 	}
+	// A task with a time of day is overdue once its time has passed, a task without one the day after
+	overdue := afterNow(now, t)
+	if task.Time != "" {
+		overdue = t.Before(now)
+	}
+
+	for _, d := range task.Exdates {
+		if _, err := time.Parse(formatDate, d); err != nil {
+			return fmt.Errorf("invalid excluded date: %s", d)
+		}
+	}
+
+	// Keep the progress of a limited series, but never beyond its total
+	var rule recur.Rule
+	if task.Repeat != "" {
+		if rule, err = recur.Parse(task.Repeat); err != nil {
+			return err
+		}
+	}
+	switch task.Anchor {
+	case "":
+		task.Anchor = db.AnchorSchedule
+	case db.AnchorSchedule, db.AnchorCompletion:
+	default:
+		return fmt.Errorf("invalid anchor, expected %s or %s: %s", db.AnchorSchedule, db.AnchorCompletion, task.Anchor)
+	}
+	switch task.CatchUp {
+	case "":
+		task.CatchUp = db.CatchUpSkip
+	case db.CatchUpSkip, db.CatchUpMaterialize, db.CatchUpToday:
+	default:
+		return fmt.Errorf("invalid catchup, expected %s, %s or %s: %s",
+			db.CatchUpSkip, db.CatchUpMaterialize, db.CatchUpToday, task.CatchUp)
+	}
+	switch task.ChecklistMode {
+	case "":
+		task.ChecklistMode = db.ChecklistFree
+	case db.ChecklistFree, db.ChecklistRequire, db.ChecklistAuto:
+	default:
+		return fmt.Errorf("invalid checklist_mode, expected %s, %s or %s: %s",
+			db.ChecklistFree, db.ChecklistRequire, db.ChecklistAuto, task.ChecklistMode)
+	}
+	if err := checkTags(task); err != nil {
+		return err
+	}
+	switch {
+	case task.Priority == 0:
+		task.Priority = db.PriorityNone
+	case task.Priority < db.PriorityUrgent || task.Priority > db.PriorityNone:
+		return fmt.Errorf("invalid priority, expected %d (urgent) to %d (none): %d", db.PriorityUrgent, db.PriorityNone, task.Priority)
+	}
+	// COUNT of an RFC 5545 rule is counted from a fixed start, which a completion-based task does not have
+	if task.Anchor == db.AnchorCompletion && rule.Kind == recur.RRule && rule.Count > 0 {
+		return fmt.Errorf("use the count modifier instead of RRULE COUNT for a completion-based task: %s", task.Repeat)
+	}
+	task.EndDate = ""
+	if !rule.Until.IsZero() {
+		task.EndDate = rule.Until.Format(formatDate)
+	}
+	if rule.Count == 0 {
+		task.Remaining = 0
+	}
+	if rule.Count > 0 && (task.Remaining <= 0 || task.Remaining > rule.Count) {
+		task.Remaining = rule.Count
+	}
 
-	next := now.Format(formatDate)
+	next, nextTime := now.Format(formatDate), task.Time
 	if len(task.Repeat) != 0 {
-		next, err = NextDate(now, task.Date, task.Repeat)
+		next, nextTime, err = NextDateTime(now, task.Date, task.Time, task.TimeZone, task.Repeat, task.Exdates...)
+		// A series that ends after the task date still has the task date itself to go
+		if errors.Is(err, recur.ErrSeriesEnded) && !overdue {
+			err = nil
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	if afterNow(now, t) {
-		if len(task.Repeat) == 0 {
+	if overdue {
+		// A chore that repeats after completion is due until it is done
+		if len(task.Repeat) == 0 || task.Anchor == db.AnchorCompletion {
 			task.Date = now.Format(formatDate)
 		}
-		if len(task.Repeat) > 0 {
-			task.Date = next
+		if len(task.Repeat) > 0 && task.Anchor == db.AnchorSchedule {
+			// The occurrences that have passed use up a limited series
+			if task.Remaining > 0 {
+				passed, err := passedOccurrences(task, occurrence{next, nextTime})
+				if err != nil {
+					return err
+				}
+				if passed >= task.Remaining {
+					return recur.ErrSeriesEnded
+				}
+				task.Remaining -= passed
+			}
+			task.Date, task.Time = next, nextTime
 		}
 	}
 	return nil
@@ -10,9 +10,28 @@ var password string // password is the password used for authentication, set dur
 func Init(mux *http.ServeMux, pass string) {
 	password = pass // Set the password for authentication
 	mux.HandleFunc("/api/nextdate", nextDayHandler)
+	mux.HandleFunc("/api/occurrences", occurrencesHandler)
+	mux.HandleFunc("/api/repeat/parse", parseRepeatHandler)
+	mux.HandleFunc("/api/repeat/describe", describeRepeatHandler)
 	mux.HandleFunc("/api/task", auth(taskHandler))
 	mux.HandleFunc("/api/tasks", auth(tasksHandler))
 	mux.HandleFunc("/api/task/done", auth(doneTaskHandler))
+	mux.HandleFunc("/api/task/exdate", auth(exdateHandler))
+	mux.HandleFunc("/api/task/missed", auth(missedHandler))
+	mux.HandleFunc("/api/task/occurrence", auth(occurrenceHandler))
+	mux.HandleFunc("/api/task/checklist", auth(checklistHandler))
+	mux.HandleFunc("/api/task/checklist/toggle", auth(toggleItemHandler))
+	mux.HandleFunc("/api/task/checklist/order", auth(reorderChecklistHandler))
+	mux.HandleFunc("/api/task/attachments", auth(attachmentsHandler))
+	mux.HandleFunc("/api/task/attachment", auth(attachmentHandler))
+	mux.HandleFunc("/api/history", auth(historyHandler))
+	mux.HandleFunc("/api/trash", auth(trashHandler))
+	mux.HandleFunc("/api/trash/restore", auth(restoreTaskHandler))
+	mux.HandleFunc("/api/tags", auth(tagsHandler))
+	mux.HandleFunc("/api/tag", auth(tagHandler))
+	mux.HandleFunc("/api/tag/merge", auth(mergeTagsHandler))
+	mux.HandleFunc("/api/projects", auth(projectsHandler))
+	mux.HandleFunc("/api/projects/{id}", auth(projectHandler))
 	mux.HandleFunc("/api/signin", signInHandler)
 }
 
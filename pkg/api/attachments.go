@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const (
+	maxAttachmentSize = 10 << 20 // maxAttachmentSize is the largest file in bytes that can be attached to a task
+	maxAttachmentName = 255      // maxAttachmentName is the longest file name of an attachment in characters
+	multipartOverhead = 1 << 20  // multipartOverhead is the room an upload request has for the multipart headers
+	multipartMemory   = 1 << 20  // multipartMemory is how much of an upload is kept in memory, the rest goes to a temporary file
+)
+
+// attachmentName returns the base name of an uploaded file, dropping any directories a client sends along with it.
+func attachmentName(filename string) (string, error) {
+	name := strings.TrimSpace(path.Base(strings.ReplaceAll(filename, `\`, "/")))
+	if name == "" || name == "." || name == "/" || utf8.RuneCountInString(name) > maxAttachmentName {
+		return name, fmt.Errorf("Имя файла должно содержать от 1 до %d символов", maxAttachmentName)
+	}
+	return name, nil
+}
+
+// attachmentsHandler handles the /api/task/attachments endpoint for the task 'id'.
+// GET lists the attachments of the task, POST uploads the multipart form field "file" as a new attachment
+// of at most maxAttachmentSize bytes.
+// Example response to GET: {"attachments":[{"id":"1","task_id":"3","name":"счёт.pdf","content_type":"application/pdf",
+// "size":48213,"created_at":"2024-01-26T09:15:00Z"}]}
+func attachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	if r.Method == http.MethodPost {
+		// The body is limited before the form is parsed, so an oversized upload is never read in full
+		r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+multipartOverhead)
+		if err := r.ParseMultipartForm(multipartMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJson(w, http.StatusRequestEntityTooLarge,
+					map[string]any{"error": fmt.Sprintf("Размер файла не должен превышать %d байт", maxAttachmentSize)})
+				return
+			}
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Ожидается файл в поле формы \"file\""})
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+	}
+	task := formTask(w, r)
+	if task == nil {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		attachments, err := db.Attachments(task.ID)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{"attachments": attachments})
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Ожидается файл в поле формы \"file\""})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAttachmentSize {
+		writeJson(w, http.StatusRequestEntityTooLarge,
+			map[string]any{"error": fmt.Sprintf("Размер файла не должен превышать %d байт", maxAttachmentSize)})
+		return
+	}
+	name, err := attachmentName(header.Filename)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	// Trust the type the client sends only if it is well-formed
+	contentType := header.Header.Get("Content-Type")
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = http.DetectContentType(data)
+	}
+
+	a := db.Attachment{TaskID: task.ID, Name: name, ContentType: contentType}
+	id, err := db.AddAttachment(&a, data)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+// attachmentHandler handles the /api/task/attachment endpoint for the attachment 'attachment' of the task 'id'.
+// GET downloads the attachment and DELETE removes it.
+func attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	task := formTask(w, r)
+	if task == nil {
+		return
+	}
+	id := r.FormValue("attachment")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указано вложение"})
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := db.DeleteAttachment(task.ID, id); err != nil {
+			writeJson(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	a, data, err := db.GetAttachment(task.ID, id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if a == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Вложение не найдено"})
+		return
+	}
+	// Uploaded files are always downloaded, never rendered by the browser as a page of this site
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
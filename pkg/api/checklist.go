@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const maxItemLength = 256 // maxItemLength is the longest checklist item in characters
+
+// checkItemText validates the text of a checklist item and returns it trimmed.
+func checkItemText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxItemLength {
+		return text, fmt.Errorf("Пункт чек-листа должен содержать от 1 до %d символов", maxItemLength)
+	}
+	return text, nil
+}
+
+// openItems returns the number of unchecked items of the task 'id'.
+func openItems(id string) (int, error) {
+	items, err := db.Checklist(id)
+	if err != nil {
+		return 0, err
+	}
+	open := 0
+	for _, item := range items {
+		if !item.Done {
+			open++
+		}
+	}
+	return open, nil
+}
+
+// autoComplete completes the current occurrence of a ChecklistAuto task once its checklist is all checked,
+// and reports whether it did.
+func autoComplete(task *db.Task) (bool, error) {
+	if task.ChecklistMode != db.ChecklistAuto {
+		return false, nil
+	}
+	items, err := db.Checklist(task.ID)
+	if err != nil || len(items) == 0 {
+		return false, err
+	}
+	for _, item := range items {
+		if !item.Done {
+			return false, nil
+		}
+	}
+	return true, completeTask(task, time.Now())
+}
+
+// itemStatus returns the HTTP status for an error changing a checklist item.
+func itemStatus(err error) int {
+	if errors.Is(err, db.ErrItemNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// checklistHandler handles the /api/task/checklist endpoint for the task 'id'.
+// GET lists the items, POST appends the item {"text":"..."}, PUT changes the item 'item' to {"text":"...","done":true}
+// and DELETE removes the item 'item'. Changes that leave every item of a ChecklistAuto task checked complete the task,
+// which the response reports as {"completed":true}.
+func checklistHandler(w http.ResponseWriter, r *http.Request) {
+	task := formTask(w, r)
+	if task == nil {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := db.Checklist(task.ID)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{"items": items})
+	case http.MethodPost:
+		var item db.ChecklistItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+			return
+		}
+		text, err := checkItemText(item.Text)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		id, err := db.AddChecklistItem(task.ID, text)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusCreated, map[string]any{"id": id})
+	case http.MethodPut, http.MethodDelete:
+		id := r.FormValue("item")
+		if id == "" {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан пункт чек-листа"})
+			return
+		}
+		var err error
+		if r.Method == http.MethodDelete {
+			err = db.DeleteChecklistItem(task.ID, id)
+		} else {
+			item := db.ChecklistItem{ID: id}
+			if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+				writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+				return
+			}
+			item.ID = id
+			if item.Text, err = checkItemText(item.Text); err != nil {
+				writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			err = db.UpdateChecklistItem(task.ID, &item)
+		}
+		if err != nil {
+			writeJson(w, itemStatus(err), map[string]any{"error": err.Error()})
+			return
+		}
+		completed, err := autoComplete(task)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{"completed": completed})
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+	}
+}
+
+// toggleItemHandler handles the /api/task/checklist/toggle endpoint. POST checks the unchecked item 'item'
+// of the task 'id' or unchecks the checked one.
+// Example response: {"done":true,"completed":false}
+func toggleItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	task := formTask(w, r)
+	if task == nil {
+		return
+	}
+	id := r.FormValue("item")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан пункт чек-листа"})
+		return
+	}
+	done, err := db.ToggleChecklistItem(task.ID, id)
+	if err != nil {
+		writeJson(w, itemStatus(err), map[string]any{"error": err.Error()})
+		return
+	}
+	completed, err := autoComplete(task)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{"done": done, "completed": completed})
+}
+
+// reorderChecklistHandler handles the /api/task/checklist/order endpoint. PUT puts the items of the task 'id'
+// in the order of the body {"items":["3","1","2"]}, which lists the IDs of all items.
+func reorderChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	task := formTask(w, r)
+	if task == nil {
+		return
+	}
+	var order struct {
+		Items []string `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+		return
+	}
+	if err := db.ReorderChecklist(task.ID, order.Items); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{})
+}
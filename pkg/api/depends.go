@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+// dependsNone in an update removes the dependency of a task, as an empty spec keeps the stored one.
+const dependsNone = "none"
+
+// checkDepends validates the dependency of a task and brings its spec to the canonical form.
+// The predecessor must exist and must not depend on the task, directly or through other tasks.
+// A dependent task without a date is due the given number of days after the current date of its predecessor.
+func checkDepends(task *db.Task) error {
+	if task.Depends == "" || task.Depends == dependsNone {
+		task.Depends = ""
+		return nil
+	}
+	dep, err := db.ParseDependency(task.Depends)
+	if err != nil {
+		return err
+	}
+	task.Depends = dep.String()
+
+	// Follow the chain of predecessors back to its start
+	var predecessor *db.Task
+	chain := []string{dep.DependsOn}
+	for id := dep.DependsOn; id != ""; {
+		if id == task.ID {
+			return fmt.Errorf("dependency cycle: %s → %s", task.ID, strings.Join(chain, " → "))
+		}
+		if len(chain) > maxWalk {
+			return fmt.Errorf("dependency chain is too long: %s", task.Depends)
+		}
+		pred, err := db.GetTask(id)
+		if err != nil {
+			return err
+		}
+		if pred == nil {
+			return fmt.Errorf("task %s to depend on not found", id)
+		}
+		if predecessor == nil {
+			predecessor = pred
+		}
+		id = ""
+		if pred.Depends != "" {
+			next, err := db.ParseDependency(pred.Depends)
+			if err != nil {
+				return err
+			}
+			id = next.DependsOn
+			chain = append(chain, id)
+		}
+	}
+
+	if task.Date == "" {
+		start, err := time.Parse(formatDate, predecessor.Date)
+		if err != nil {
+			return err
+		}
+		task.Date = start.AddDate(0, 0, dep.Days).Format(formatDate)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+// exdateHandler handles the /api/task/exdate endpoint.
+// POST excludes the date given by 'date' from the occurrences of the task 'id', DELETE removes the exclusion.
+// Excluding the current date of a repeating task moves the task to its next occurrence.
+func exdateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.FormValue("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан ID задачи"})
+		return
+	}
+	date := r.FormValue("date")
+	if _, err := time.Parse(formatDate, date); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты, ожидается YYYYMMDD"})
+		return
+	}
+	task, err := db.GetTask(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if task == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Задача не найдена"})
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := db.DeleteExdate(id, date); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	if status, err := skipOccurrence(task, date); err != nil {
+		writeJson(w, status, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{})
+}
+
+// skipOccurrence excludes the date from the occurrences of a repeating task and drops its override.
+// Excluding the current date moves the task to its next occurrence. As in RFC 5545, an excluded date
+// counts toward a limited series either way.
+// On failure it returns the HTTP status to respond with.
+func skipOccurrence(task *db.Task, date string) (int, error) {
+	if len(task.Repeat) == 0 {
+		return http.StatusBadRequest, errors.New("Исключать даты можно только у повторяющихся задач")
+	}
+	if date == task.Date {
+		// Skip the current occurrence right away
+		start, err := taskStart(task)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		next, nextTime, err := NextDateTime(start, task.Date, task.Time, task.TimeZone, task.Repeat,
+			append(slices.Clone(task.Exdates), date)...)
+		if errors.Is(err, recur.ErrSeriesEnded) {
+			return http.StatusBadRequest, errors.New("Нельзя исключить последнее повторение задачи")
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		// An excluded date still counts toward a limited series, the same as one excluded ahead of time
+		used, err := passedOccurrences(task, occurrence{next, nextTime})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if task.Remaining > 0 && used >= task.Remaining {
+			return http.StatusBadRequest, errors.New("Нельзя исключить последнее повторение задачи")
+		}
+		if err := db.SkipDate(task.ID, date, next, nextTime, used); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusOK, nil
+	}
+	if err := db.AddExdate(task.ID, date); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err := db.DeleteOverride(task.ID, date); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const limitHistory = 100 // limitHistory is the maximum number of completions /api/history returns
+
+// historyHandler handles the /api/history endpoint. It lists the completed occurrences of tasks, the latest first,
+// done from 'from' to 'to' (YYYYMMDD, both included, either may be omitted), and only of the task 'id' if it is given.
+// Example response: {"history":[{"id":"7","task_id":"3","title":"Полить цветы","date":"20240126","time":"",
+// "completed_at":"2024-01-27T09:15:00+03:00"}]}
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	from, to := r.FormValue("from"), r.FormValue("to")
+	if from != "" {
+		if _, err := time.Parse(formatDate, from); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты 'from', ожидается YYYYMMDD"})
+			return
+		}
+	}
+	if to != "" {
+		if _, err := time.Parse(formatDate, to); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты 'to', ожидается YYYYMMDD"})
+			return
+		}
+	}
+	if from != "" && to != "" && to < from {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный диапазон дат: 'to' должна быть не раньше 'from'"})
+		return
+	}
+
+	history, err := db.History(from, to, r.FormValue("id"), limitHistory)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{"history": history})
+}
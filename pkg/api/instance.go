@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+const maxWalk = 10000 // maxWalk caps the number of occurrences of a task walked through at once
+
+// occurrence is a date and time of day of a task; time is empty for tasks that take the whole day.
+type occurrence struct {
+	date, time string
+}
+
+func (o occurrence) String() string {
+	if o.time == "" {
+		return o.date
+	}
+	return o.date + " " + o.time
+}
+
+// occurrenceIter walks the occurrences of a repeating task after the stored one, with its rule parsed once.
+type occurrenceIter struct {
+	it  *recur.Iterator
+	tm  string         // tm is the time of day kept by rules with a day granularity
+	loc *time.Location // loc is the time zone of rules that move the time of day, nil for the others
+}
+
+// newOccurrenceIter returns an iterator that starts at the stored occurrence of the repeating task.
+func newOccurrenceIter(task *db.Task) (*occurrenceIter, error) {
+	rule, err := recur.Parse(task.Repeat)
+	if err != nil {
+		return nil, err
+	}
+	if task.Time != "" && rule.HasTime() {
+		if rule.Start, err = taskStart(task); err != nil {
+			return nil, err
+		}
+		return &occurrenceIter{it: rule.IterateTime(), loc: rule.Start.Location()}, nil
+	}
+	if rule.Start, err = time.Parse(formatDate, task.Date); err != nil {
+		return nil, err
+	}
+	return &occurrenceIter{it: rule.Iterate(), tm: task.Time}, nil
+}
+
+// next returns the occurrence after the previous one, as NextDateTime would.
+func (o *occurrenceIter) next() (occurrence, error) {
+	t, err := o.it.Next()
+	if err != nil {
+		return occurrence{}, err
+	}
+	if o.loc == nil {
+		return occurrence{t.Format(formatDate), o.tm}, nil
+	}
+	t = t.In(o.loc)
+	return occurrence{t.Format(formatDate), t.Format(formatTime)}, nil
+}
+
+// walkOccurrences calls fn for the occurrences of the task in order, starting with the stored one,
+// until fn returns false, the series ends or maxWalk occurrences have been visited.
+// start is the moment the occurrence is scheduled for in the task's time zone.
+// Excluded dates are not visited, but they count toward the remaining occurrences of a limited series.
+// Every occurrence is a single step from the previous one, so the work grows with the occurrences walked.
+func walkOccurrences(task *db.Task, fn func(o occurrence, start time.Time) bool) error {
+	var iter *occurrenceIter
+	if task.Repeat != "" {
+		var err error
+		if iter, err = newOccurrenceIter(task); err != nil {
+			return err
+		}
+	}
+	cur := occurrence{task.Date, task.Time}
+	for n := 1; n <= maxWalk; n++ {
+		t, err := taskStart(&db.Task{Date: cur.date, Time: cur.time, TimeZone: task.TimeZone})
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(task.Exdates, cur.date) && !fn(cur, t) {
+			return nil
+		}
+		if task.Repeat == "" || n == task.Remaining {
+			return nil
+		}
+		cur, err = iter.next()
+		if errors.Is(err, recur.ErrSeriesEnded) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passedOccurrences returns the number of occurrences of the task from the stored one up to, but not including,
+// the occurrence o. Excluded dates are among them, as they count toward RRULE COUNT and the count modifier alike.
+func passedOccurrences(task *db.Task, o occurrence) (int, error) {
+	iter, err := newOccurrenceIter(task)
+	if err != nil {
+		return 0, err
+	}
+	cur := occurrence{task.Date, task.Time}
+	n := 0
+	for ; n < maxWalk && cur.String() < o.String(); n++ {
+		cur, err = iter.next()
+		if errors.Is(err, recur.ErrSeriesEnded) {
+			return n + 1, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// instance returns the occurrence of the series with its override, if any, applied.
+func instance(task *db.Task, o occurrence, override *db.Override) *db.Task {
+	inst := *task
+	inst.Date, inst.Time = o.date, o.time
+	if task.Repeat == "" {
+		return &inst
+	}
+	inst.Occurrence = o.date
+	if override == nil {
+		return &inst
+	}
+	if override.NewDate != "" {
+		inst.Date = override.NewDate
+	}
+	if override.Time != "" {
+		inst.Time = override.Time
+	}
+	if override.Comment != "" {
+		inst.Comment = override.Comment
+	}
+	return &inst
+}
+
+// findOverride returns the override of the occurrence scheduled on the date, or nil.
+func findOverride(overrides []db.Override, date string) *db.Override {
+	for i := range overrides {
+		if overrides[i].Date == date {
+			return &overrides[i]
+		}
+	}
+	return nil
+}
+
+// currentInstances replaces every repeating task with its current occurrence, overrides applied.
+func currentInstances(tasks []*db.Task) ([]*db.Task, error) {
+	res := make([]*db.Task, 0, len(tasks))
+	for _, task := range tasks {
+		var overrides []db.Override
+		if task.Repeat != "" {
+			var err error
+			if overrides, err = db.Overrides(task.ID); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, instance(task, occurrence{task.Date, task.Time}, findOverride(overrides, task.Date)))
+	}
+	sortInstances(res)
+	return res, nil
+}
+
+// expandInstances returns the occurrences of the tasks that fall on the dates from to to inclusive
+// (YYYYMMDD) once their overrides are applied.
+func expandInstances(tasks []*db.Task, from, to string) ([]*db.Task, error) {
+	res := []*db.Task{}
+	for _, task := range tasks {
+		var overrides []db.Override
+		if task.Repeat != "" {
+			var err error
+			if overrides, err = db.Overrides(task.ID); err != nil {
+				return nil, err
+			}
+		}
+		// An occurrence scheduled after the range may have been moved into it
+		last := to
+		for _, o := range overrides {
+			last = max(last, o.Date)
+		}
+		err := walkOccurrences(task, func(o occurrence, _ time.Time) bool {
+			if o.date > last {
+				return false
+			}
+			if inst := instance(task, o, findOverride(overrides, o.date)); inst.Date >= from && inst.Date <= to {
+				res = append(res, inst)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sortInstances(res)
+	return res, nil
+}
+
+// sortInstances orders the occurrences by date, priority and time of day.
+func sortInstances(tasks []*db.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Date != tasks[j].Date {
+			return tasks[i].Date < tasks[j].Date
+		}
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority < tasks[j].Priority
+		}
+		return tasks[i].Time < tasks[j].Time
+	})
+}
+
+// findOccurrence checks that the series of a repeating task with a day granularity schedules an occurrence on the date.
+func findOccurrence(task *db.Task, date string) (occurrence, error) {
+	if task.Repeat == "" {
+		return occurrence{}, fmt.Errorf("Отдельные повторения есть только у повторяющихся задач")
+	}
+	rule, err := recur.Parse(task.Repeat)
+	if err != nil {
+		return occurrence{}, err
+	}
+	if rule.HasTime() {
+		return occurrence{}, fmt.Errorf("Отдельные повторения можно менять только у задач, повторяющихся по дням")
+	}
+	var found *occurrence
+	err = walkOccurrences(task, func(o occurrence, _ time.Time) bool {
+		if o.date == date {
+			found = &o
+		}
+		return o.date < date
+	})
+	if err != nil {
+		return occurrence{}, err
+	}
+	if found == nil {
+		return occurrence{}, errOccurrenceNotFound
+	}
+	return *found, nil
+}
+
+var errOccurrenceNotFound = errors.New("Повторение задачи не найдено")
+
+// occurrenceHandler handles the /api/task/occurrence endpoint for the occurrence of the repeating task 'id'
+// scheduled on 'date' (YYYYMMDD), leaving the rest of the series as it is.
+// GET returns the occurrence. PUT moves it or gives it its own comment: the body is
+// {"date":"20240503","time":"10:00","comment":"..."}, empty fields keep the values of the series
+// and an empty body restores the occurrence. DELETE skips the occurrence.
+func occurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.FormValue("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан ID задачи"})
+		return
+	}
+	date := r.FormValue("date")
+	if _, err := time.Parse(formatDate, date); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты, ожидается YYYYMMDD"})
+		return
+	}
+	task, err := db.GetTask(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if task == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Задача не найдена"})
+		return
+	}
+	o, err := findOccurrence(task, date)
+	if errors.Is(err, errOccurrenceNotFound) {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		overrides, err := db.Overrides(id)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, instance(task, o, findOverride(overrides, date)))
+	case http.MethodPut:
+		var change struct {
+			Date    string `json:"date"`
+			Time    string `json:"time"`
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+			return
+		}
+		if _, err := time.Parse(formatDate, change.Date); change.Date != "" && err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты, ожидается YYYYMMDD"})
+			return
+		}
+		if change.Time != "" {
+			if err := checkTime(change.Time); err != nil {
+				writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+		}
+		if change.Date == "" && change.Time == "" && change.Comment == "" {
+			err = db.DeleteOverride(id, date)
+		} else {
+			err = db.SetOverride(id, db.Override{Date: date, NewDate: change.Date, Time: change.Time, Comment: change.Comment})
+		}
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+	case http.MethodDelete:
+		if status, err := skipOccurrence(task, date); err != nil {
+			writeJson(w, status, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+	}
+}
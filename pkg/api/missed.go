@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+// missedOccurrences returns the occurrences of the task that have passed by now, oldest first,
+// starting with the stored date itself. A task without a time of day is missed the day after its date.
+// At most maxWalk occurrences are returned.
+func missedOccurrences(task *db.Task, now time.Time) ([]occurrence, error) {
+	loc, err := loadLocation(task.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	now = now.In(loc)
+
+	var missed []occurrence
+	err = walkOccurrences(task, func(o occurrence, start time.Time) bool {
+		passed := start.Before(now)
+		if o.time == "" {
+			passed = afterNow(now, start)
+		}
+		if passed {
+			missed = append(missed, o)
+		}
+		return passed
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missed, nil
+}
+
+// missedHandler handles the /api/task/missed endpoint.
+// It returns how many occurrences of the task 'id' have passed without the task being done,
+// and their dates ("YYYYMMDD" or "YYYYMMDD HH:MM"), at most maxOccurrences of them, most recent last.
+// Example response: {"missed":3,"dates":["20240120","20240123","20240126"]}
+func missedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.FormValue("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан ID задачи"})
+		return
+	}
+	task, err := db.GetTask(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if task == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Задача не найдена"})
+		return
+	}
+	missed, err := missedOccurrences(task, time.Now())
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	dates := make([]string, 0, min(len(missed), maxOccurrences))
+	for _, o := range missed[max(len(missed)-maxOccurrences, 0):] {
+		dates = append(dates, o.String())
+	}
+	writeJson(w, http.StatusOK, map[string]any{"missed": len(missed), "dates": dates})
+}
+
+// catchUp applies the catch-up policy of a task that is done late to its next date and time, empty once the series
+// has ended, and returns the tasks to add for the missed occurrences.
+// The occurrence being done is the first of the missed ones; the others are skipped,
+// added as separate overdue tasks or rolled into one due today.
+func catchUp(task *db.Task, now time.Time, next, nextTime string) (string, string, []*db.Task, error) {
+	if task.CatchUp == db.CatchUpSkip || task.CatchUp == "" {
+		return next, nextTime, nil, nil
+	}
+	missed, err := missedOccurrences(task, now)
+	if err != nil || len(missed) == 0 {
+		return next, nextTime, nil, err
+	}
+
+	if task.CatchUp == db.CatchUpToday {
+		if len(missed) < 2 || next == "" {
+			return next, nextTime, nil, nil
+		}
+		loc, err := loadLocation(task.TimeZone)
+		if err != nil {
+			return next, nextTime, nil, err
+		}
+		return now.In(loc).Format(formatDate), task.Time, nil, nil
+	}
+
+	// Every occurrence is kept, so the task moves to the one right after the missed ones, even if it is due today
+	last := missed[len(missed)-1]
+	start, err := taskStart(&db.Task{Date: last.date, Time: last.time, TimeZone: task.TimeZone})
+	if err != nil {
+		return next, nextTime, nil, err
+	}
+	next, nextTime, err = NextDateTime(start, task.Date, task.Time, task.TimeZone, task.Repeat, task.Exdates...)
+	if errors.Is(err, recur.ErrSeriesEnded) {
+		next, nextTime, err = "", "", nil
+	}
+	if err != nil {
+		return next, nextTime, nil, err
+	}
+
+	// Only the most recent ones are worth adding
+	missed = missed[1:]
+	var tasks []*db.Task
+	for _, o := range missed[max(len(missed)-maxOccurrences, 0):] {
+		tasks = append(tasks, &db.Task{
+			Date:          o.date,
+			Title:         task.Title,
+			Comment:       task.Comment,
+			Time:          o.time,
+			TimeZone:      task.TimeZone,
+			Anchor:        db.AnchorSchedule,
+			CatchUp:       db.CatchUpSkip,
+			Priority:      task.Priority,
+			Tags:          task.Tags,
+			ProjectID:     task.ProjectID,
+			ChecklistMode: db.ChecklistFree,
+		})
+	}
+	return next, nextTime, tasks, nil
+}
@@ -1,22 +1,34 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/somepgs/go_final_project/pkg/recur"
 )
 
 const formatDate = "20060102" // Format for date in YYYYMMDD format
 
+const (
+	defaultOccurrences = 10  // defaultOccurrences is the number of dates /api/occurrences returns when 'count' is omitted
+	maxOccurrences     = 100 // maxOccurrences caps the number of dates returned by Occurrences
+)
+
 // nextDayHandler handles the /api/nextdate endpoint.
 // It expects 'now', 'date', and 'repeat' parameters in the request.
 // 'now' is the current date in YYYYMMDD format, 'date' is the start date in YYYYMMDD format,
-// and 'repeat' is the repeat pattern (e.g., "d 7" for every 7 days, "y" for yearly).
+// and 'repeat' is the repeat pattern (e.g., "d 7" for every 7 days, "y" for yearly,
+// or an RFC 5545 rule such as "RRULE:FREQ=MONTHLY;BYDAY=-1FR").
 // It returns the next date based on the repeat pattern.
 // If 'now' is not provided, it defaults to the current date.
+// The optional 'exdates' parameter lists comma-separated dates to skip.
+// For a task with a time of day, 'time' (HH:MM) and optionally 'tz' are passed, 'now' may carry a time
+// as "YYYYMMDD HH:MM", and the response is "YYYYMMDD HH:MM".
 // If 'date' or 'repeat' is empty, it returns an error.
 // If the date format is invalid, it returns an error.
 // The response is in YYYYMMDD format.
@@ -27,24 +39,34 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	now := r.FormValue("now")
-	if now == "" {
-		now = time.Now().Format(formatDate)
-	}
 	date := r.FormValue("date")
 	repeat := r.FormValue("repeat")
+	tm := r.FormValue("time")
 
-	nowTime, err := time.Parse(formatDate, now)
+	loc, err := loadLocation(r.FormValue("tz"))
 	if err != nil {
-		http.Error(w, "Invalid 'now' date format, expected YYYYMMDD", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	nowTime := time.Now()
+	if now := r.FormValue("now"); len(now) > len(formatDate) {
+		nowTime, err = time.ParseInLocation(formatDate+" "+formatTime, now, loc)
+	} else if now != "" {
+		nowTime, err = time.ParseInLocation(formatDate, now, loc)
+	}
+	if err != nil {
+		http.Error(w, "Invalid 'now' date format, expected YYYYMMDD or YYYYMMDD HH:MM", http.StatusBadRequest)
 		return
 	}
 
-	nextDate, err := NextDate(nowTime, date, repeat)
+	nextDate, nextTime, err := NextDateTime(nowTime, date, tm, r.FormValue("tz"), repeat, splitDates(r.FormValue("exdates"))...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if nextTime != "" {
+		nextDate += " " + nextTime
+	}
 
 	_, err = w.Write([]byte(nextDate))
 	if err != nil {
@@ -54,203 +76,156 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // NextDate calculates the next date based on the provided start date and repeat pattern.
-func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+// Dates listed in exclude (YYYYMMDD) are skipped.
+func NextDate(now time.Time, dstart string, repeat string, exclude ...string) (string, error) {
 	if dstart == "" {
 		return "", fmt.Errorf("data cannot be empty")
 	}
 	if repeat == "" {
 		return "", fmt.Errorf("repeat cannot be empty")
 	}
-	date, err := time.Parse(formatDate, dstart)
+	start, err := time.Parse(formatDate, dstart)
 	if err != nil {
 		return "", err
 	}
-	date, err = changeRepeat(date, now, repeat) // Change the date based on the repeat pattern
+	rule, err := recur.Parse(repeat)
 	if err != nil {
 		return "", err
 	}
-	return date.Format(formatDate), nil
-}
-
-// changeRepeat modifies the date based on the repeat pattern.
-func changeRepeat(date, now time.Time, repeat string) (time.Time, error) {
-	arr := strings.Split(repeat, " ")
-	switch arr[0] {
-	case "d":
-		if len(arr) != 2 {
-			return date, fmt.Errorf("invalid repeat format for daily: %s", repeat)
-		}
-		interval, err := strconv.Atoi(arr[1])
+	rule.Start = start
+	for {
+		date, err := rule.Next(now) // Change the date based on the repeat pattern
 		if err != nil {
-			return date, err
-		}
-		if interval <= 0 || interval >= 400 {
-			return date, fmt.Errorf("invalid interval for daily: %d", interval)
-		}
-		return addDay(date, now, interval), nil
-	case "y":
-		if len(arr) >= 2 {
-			return date, fmt.Errorf("invalid repeat format for yearly: %s", repeat)
+			return "", err
 		}
-		return addYear(date, now, 1), nil
-	case "w":
-		if len(arr) < 2 {
-			return date, fmt.Errorf("the number of parameters cannot be less than two for days of the week: %s", repeat)
+		next := date.Format(formatDate)
+		if !slices.Contains(exclude, next) {
+			return next, nil
 		}
-		return addWeek(date, now, arr[1])
-	case "m":
-		if len(arr) < 2 {
-			return date, fmt.Errorf("invalid repeat format for monthly: %s", repeat)
-		}
-		return addMonth(date, now, arr[1:])
-	default:
-		return date, fmt.Errorf("unsupported repeat type: %s", arr[0])
+		now = date // Every excluded date moves now forward, so the loop ends
 	}
 }
 
-// afterNow checks if the date is after the current time.
-func afterNow(date, now time.Time) bool {
-	dy, dm, dd := date.Date()
-	ny, nm, nd := now.Date()
-
-	if dy != ny {
-		return dy > ny
-	}
-
-	if dm != nm {
-		return dm > nm
+// Occurrences returns up to count upcoming dates of the repeat pattern after now, in order.
+// If until is not zero, dates after it are not included. Dates listed in exclude are skipped.
+// The list ends early when the rule runs out of occurrences; a rule that produces no date at all is an error.
+func Occurrences(now time.Time, dstart string, repeat string, count int, until time.Time, exclude ...string) ([]string, error) {
+	if count <= 0 || count > maxOccurrences {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxOccurrences)
+	}
+	next := func() (string, error) {
+		return NextDate(now, dstart, repeat, exclude...)
+	}
+	if rule, err := recur.Parse(repeat); err == nil && rule.Count > 0 && dstart != "" {
+		// A limited series is walked once instead of from its start for every date
+		if rule.Start, err = time.Parse(formatDate, dstart); err != nil {
+			return nil, err
+		}
+		it := rule.Iterate()
+		next = func() (string, error) {
+			for {
+				date, err := it.Next()
+				if err != nil {
+					return "", err
+				}
+				if d := date.Format(formatDate); afterNow(date, now) && !slices.Contains(exclude, d) {
+					return d, nil
+				}
+			}
+		}
 	}
-
-	return dd > nd
-}
-
-// addDay adds the specified interval of days to the date until it is after now.
-// It returns the modified date.
-func addDay(date, now time.Time, interval int) time.Time {
-	for {
-		date = date.AddDate(0, 0, interval)
-		if afterNow(date, now) {
+	dates := make([]string, 0, count)
+	for len(dates) < count {
+		date, err := next()
+		if errors.Is(err, recur.ErrSeriesEnded) && len(dates) > 0 {
 			break
 		}
-	}
-	return date
-}
-
-// addYear adds the specified interval of years to the date until it is after now.
-// It returns the modified date.
-func addYear(date, now time.Time, interval int) time.Time {
-	for {
-		date = date.AddDate(interval, 0, 0)
-		if afterNow(date, now) {
+		if err != nil {
+			return nil, err
+		}
+		now, err = time.Parse(formatDate, date)
+		if err != nil {
+			return nil, err
+		}
+		if !until.IsZero() && afterNow(now, until) {
 			break
 		}
+		dates = append(dates, date)
 	}
-	return date
+	return dates, nil
 }
 
-func addWeek(date, now time.Time, interval string) (time.Time, error) {
-	// If the date is before now, set it to now
-	for {
-		date = date.AddDate(0, 0, 1)
-		if afterNow(date, now) {
-			break
-		}
+// occurrencesHandler handles the /api/occurrences endpoint.
+// It expects 'date' and 'repeat' parameters like /api/nextdate, plus optional 'now',
+// 'count' (10 by default, at most maxOccurrences), 'until' in YYYYMMDD format and 'exdates'.
+// Example request: /api/occurrences?now=20240126&date=20240113&repeat=d 7&count=3
+// Example response: {"dates":["20240127","20240203","20240210"]}
+func occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Method not allowed"})
+		return
 	}
-
-	daysOfWeek := make(map[int]bool)
-	interval = strings.TrimSpace(interval) // Trim whitespace from the interval string
-	days := strings.Split(interval, ",")   // Split the interval by comma to get individual days
-
-	// Iterate over the provided days of the week
-	for _, val := range days {
-		day, err := strconv.Atoi(val)
-		if err != nil || day < 1 || day > 7 {
-			return date, fmt.Errorf("invalid day value: %s", val)
+	nowTime := time.Now()
+	if now := r.FormValue("now"); now != "" {
+		var err error
+		nowTime, err = time.Parse(formatDate, now)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Invalid 'now' date format, expected YYYYMMDD"})
+			return
 		}
-		day = day % 7          // Normalize the day to 0-6 range (1-7 to 0-6)
-		daysOfWeek[day] = true // Mark the day as set
 	}
-	today := int(date.Weekday())
-	for i := 0; i < 7; i++ {
-		nextDay := (today + i) % 7 // Calculate the next day in the week
-		if daysOfWeek[nextDay] {   // Check if this day is in the provided days of the week
-			return date.AddDate(0, 0, i), nil // Add the number of days to reach this day
+	count := defaultOccurrences
+	if c := r.FormValue("count"); c != "" {
+		var err error
+		count, err = strconv.Atoi(c)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Invalid 'count', expected a number"})
+			return
 		}
 	}
-	return date, fmt.Errorf("no valid days of the week provided in interval: %s", interval)
-}
-
-func addMonth(date, now time.Time, interval []string) (time.Time, error) {
-	// If the date is before now, set it to now
-	for {
-		date = date.AddDate(0, 0, 1)
-		if afterNow(date, now) {
-			break
+	var untilTime time.Time
+	if until := r.FormValue("until"); until != "" {
+		var err error
+		untilTime, err = time.Parse(formatDate, until)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Invalid 'until' date format, expected YYYYMMDD"})
+			return
 		}
 	}
 
-	daysOfMonth := strings.Split(strings.TrimSpace(interval[0]), ",")
-	var months []string
-	// If the interval has more than one part, the second part contains the months
-	if len(interval) > 1 {
-		months = strings.Split(strings.TrimSpace(interval[1]), ",")
+	dates, err := Occurrences(nowTime, r.FormValue("date"), r.FormValue("repeat"), count, untilTime,
+		splitDates(r.FormValue("exdates"))...)
+	if errors.Is(err, recur.ErrSeriesEnded) {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "The repeat rule never produces a date after 'now'"})
+		return
 	}
-	if len(interval) == 1 {
-		months = []string{} // Default to empty if no months are provided
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
 	}
+	writeJson(w, http.StatusOK, map[string]any{"dates": dates})
+}
 
-	monthMap := map[int]bool{}
-	if len(months) == 0 {
-		for i := 1; i <= 12; i++ {
-			monthMap[i] = true
-		}
-	}
-	if len(months) > 0 {
-		for _, m := range months {
-			m, err := strconv.Atoi(m)
-			if err != nil || m < 1 || m > 12 {
-				return time.Time{}, fmt.Errorf("invalid month: %d", m)
-			}
-			monthMap[m] = true
-		}
+// splitDates splits a comma-separated list of dates, returning nil for an empty string.
+func splitDates(dates string) []string {
+	if dates == "" {
+		return nil
 	}
+	return strings.Split(dates, ",")
+}
 
-	for i := 0; i < 24; i++ {
-		nextMonth := date.AddDate(0, i, 0)
-		monthNum := int(nextMonth.Month())
-		if !monthMap[monthNum] {
-			continue
-		}
-		lastDay := lastDayOfMonth(nextMonth)
-
-		var candidates []int
-		for _, d := range daysOfMonth {
-			day, err := strconv.Atoi(d)
-			if err != nil || day < -2 || day > 31 || day == 0 {
-				return date, fmt.Errorf("invalid day: %s", d)
-			}
-			switch {
-			case day > 0 && day <= lastDay:
-				candidates = append(candidates, day)
-			case day == -1:
-				candidates = append(candidates, lastDay)
-			case day == -2 && lastDay > 1:
-				candidates = append(candidates, lastDay-1)
-			}
-		}
+// afterNow checks if the date is after the current time.
+func afterNow(date, now time.Time) bool {
+	dy, dm, dd := date.Date()
+	ny, nm, nd := now.Date()
 
-		sort.Ints(candidates) // Sort candidates to find the next valid date
+	if dy != ny {
+		return dy > ny
+	}
 
-		for _, d := range candidates {
-			candidateDate := time.Date(nextMonth.Year(), nextMonth.Month(), d, 0, 0, 0, 0, date.Location())
-			if candidateDate.After(date) {
-				return candidateDate, nil
-			}
-		}
+	if dm != nm {
+		return dm > nm
 	}
-	return date, fmt.Errorf("cannot find suitable date for given rules")
-}
 
-func lastDayOfMonth(t time.Time) int {
-	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return dd > nd
 }
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/phrase"
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+// parseRepeatHandler turns a phrase like "every 2 weeks on Monday" into a repeat string.
+func parseRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Method not allowed"})
+		return
+	}
+	repeat, err := phrase.Parse(r.FormValue("text"))
+	if err == nil {
+		err = checkRepeat(repeat)
+	}
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{"repeat": repeat})
+}
+
+// describeRepeatHandler renders a repeat string as a sentence in the language given by lang.
+func describeRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Method not allowed"})
+		return
+	}
+	repeat := r.FormValue("repeat")
+	if err := checkRepeat(repeat); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	text, err := phrase.Describe(repeat, r.FormValue("lang"))
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{"text": text})
+}
+
+// checkRepeat validates a repeat string by scheduling a task with a time of day from today.
+// A series that has already ended is still a valid rule.
+func checkRepeat(repeat string) error {
+	now := time.Now()
+	_, _, err := NextDateTime(now, now.Format(formatDate), "00:00", "", repeat)
+	if errors.Is(err, recur.ErrSeriesEnded) {
+		return nil
+	}
+	return err
+}
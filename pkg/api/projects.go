@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const maxProjectName = 128 // maxProjectName is the longest project name in characters
+
+var colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`) // colorRe matches a color in #RRGGBB format
+
+// checkProject validates the project of a task. A task can be moved into an archived project
+// only if it is already there; "0" takes the task out of its project.
+func checkProject(task *db.Task, stored *db.Task) error {
+	if task.ProjectID == "0" {
+		task.ProjectID = ""
+	}
+	if task.ProjectID == "" {
+		return nil
+	}
+	project, err := db.GetProject(task.ProjectID)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return fmt.Errorf("project not found: %s", task.ProjectID)
+	}
+	if project.Archived && (stored == nil || stored.ProjectID != project.ID) {
+		return fmt.Errorf("project is archived: %s", project.Name)
+	}
+	return nil
+}
+
+// checkProjectFields validates the name and color of a project and trims the name.
+func checkProjectFields(p *db.Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > maxProjectName {
+		return fmt.Errorf("Название проекта должно содержать от 1 до %d символов", maxProjectName)
+	}
+	if p.Color != "" && !colorRe.MatchString(p.Color) {
+		return fmt.Errorf("Неверный цвет, ожидается формат #RRGGBB: %s", p.Color)
+	}
+	p.Color = strings.ToLower(p.Color)
+	if p.Position < 0 {
+		return fmt.Errorf("Неверная позиция проекта: %d", p.Position)
+	}
+	return nil
+}
+
+// projectsHandler handles the /api/projects endpoint.
+// GET lists the projects, with the archived ones if 'archived' is set; POST adds a project.
+// Example response: {"projects":[{"id":"1","name":"Дом","color":"#00aa00","archived":false,"position":1}]}
+func projectsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		projects, err := db.Projects(r.FormValue("archived") == "1" || r.FormValue("archived") == "true")
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{"projects": projects})
+	case http.MethodPost:
+		var p db.Project
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+			return
+		}
+		if err := checkProjectFields(&p); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		id, err := db.AddProject(&p)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusCreated, map[string]any{"id": id})
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+	}
+}
+
+// projectHandler handles the /api/projects/{id} endpoint.
+// GET returns the project, PUT updates it and DELETE removes it, keeping its tasks without a project.
+func projectHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	project, err := db.GetProject(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if project == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Проект не найден"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, project)
+	case http.MethodPut:
+		var p db.Project
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат данных"})
+			return
+		}
+		p.ID = project.ID
+		if err := checkProjectFields(&p); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		if err := db.UpdateProject(&p); err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+	case http.MethodDelete:
+		if err := db.DeleteProject(project.ID); err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{})
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+const maxTagLength = 64 // maxTagLength is the longest tag name in characters
+
+// checkTags trims the tag names of a task and drops the repeated ones, which differ in case at most.
+func checkTags(task *db.Task) error {
+	if task.Tags == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(task.Tags))
+	for _, name := range task.Tags {
+		name, err := checkTagName(name)
+		if err != nil {
+			return err
+		}
+		dup := false
+		for _, t := range tags {
+			dup = dup || strings.EqualFold(t, name)
+		}
+		if !dup {
+			tags = append(tags, name)
+		}
+	}
+	task.Tags = tags
+	return nil
+}
+
+// checkTagName validates a tag name and returns it trimmed.
+func checkTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxTagLength || strings.Contains(name, ",") {
+		return name, fmt.Errorf("invalid tag, expected 1 to %d characters without commas: %q", maxTagLength, name)
+	}
+	return name, nil
+}
+
+// tagsHandler handles the /api/tags endpoint. It lists all tags with the number of tasks of each.
+// Example response: {"tags":[{"name":"дом","tasks":3},{"name":"работа","tasks":5}]}
+func tagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	tags, err := db.Tags()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+// tagHandler handles the /api/tag endpoint for the tag 'name'.
+// PUT renames it to 'new', DELETE removes it from all tasks and deletes it.
+func tagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан тег"})
+		return
+	}
+
+	var err error
+	if r.Method == http.MethodDelete {
+		err = db.DeleteTag(name)
+	} else {
+		var newName string
+		if newName, err = checkTagName(r.FormValue("new")); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		err = db.RenameTag(name, newName)
+	}
+	if errors.Is(err, db.ErrTagNotFound) {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Тег не найден"})
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{})
+}
+
+// mergeTagsHandler handles the /api/tag/merge endpoint.
+// It moves the tasks of the comma-separated tags 'from' to the tag 'into' and deletes the former.
+// Example request: POST /api/tag/merge?from=покупки,магазин&into=дела
+func mergeTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	into, err := checkTagName(r.FormValue("into"))
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	var names []string
+	for _, name := range strings.Split(r.FormValue("from"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указаны теги для объединения"})
+		return
+	}
+	err = db.MergeTags(names, into)
+	if errors.Is(err, db.ErrTagNotFound) {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{})
+}
@@ -2,11 +2,33 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
 	"net/http"
 	"time"
 )
 
+// formTask reads the task 'id' of a request. If there is none, it responds with an error and returns nil.
+func formTask(w http.ResponseWriter, r *http.Request) *db.Task {
+	id := r.FormValue("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан ID задачи"})
+		return nil
+	}
+	task, err := db.GetTask(id)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return nil
+	}
+	if task == nil {
+		writeJson(w, http.StatusNotFound, map[string]any{"error": "Задача не найдена"})
+		return nil
+	}
+	return task
+}
+
 func getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if id == "" {
@@ -36,13 +58,62 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Заголовок задачи не может быть пустым"})
 		return
 	}
+	stored, err := db.GetTask(task.ID)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if stored != nil {
+		// Keep the progress of a limited series unless the client sets it or changes the rule
+		if task.Remaining == 0 && stored.Repeat == task.Repeat {
+			task.Remaining = stored.Remaining
+		}
+		// Excluded dates are managed by /api/task/exdate
+		task.Exdates = stored.Exdates
+		// Clients that do not know about anchors, catch-up policies, dependencies, priorities and checklist modes
+		// keep the stored ones
+		if task.Anchor == "" {
+			task.Anchor = stored.Anchor
+		}
+		if task.CatchUp == "" {
+			task.CatchUp = stored.CatchUp
+		}
+		if task.Depends == "" {
+			task.Depends = stored.Depends
+		}
+		if task.Priority == 0 {
+			task.Priority = stored.Priority
+		}
+		if task.ChecklistMode == "" {
+			task.ChecklistMode = stored.ChecklistMode
+		}
+		// Tags are kept when left out and cleared with an empty list
+		if task.Tags == nil {
+			task.Tags = stored.Tags
+		}
+		// The project is kept when left out and cleared with "0"
+		if task.ProjectID == "" {
+			task.ProjectID = stored.ProjectID
+		}
+	}
+	// An unchanged dependency stays as it is, even while the task it follows is in the trash
+	if stored == nil || task.Depends != stored.Depends || task.Date == "" {
+		if err := checkDepends(&task); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+	}
+	if err := checkProject(&task, stored); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
 	// Check if the date is valid
 	if err := checkDate(&task); err != nil {
 		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 		return
 	}
 	// Update the task in the database
-	err := db.UpdateTask(&task)
+	err = db.UpdateTask(&task)
 	if err != nil {
 		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
@@ -70,31 +141,121 @@ func doneTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeJson(w, http.StatusNotFound, map[string]any{"error": "Задача не найдена"})
 		return
 	}
-	// If the task has no repeat, delete it; otherwise, update the date
-	if len(task.Repeat) == 0 {
-		err = db.DeleteTask(id)
+	if task.ChecklistMode == db.ChecklistRequire {
+		open, err := openItems(task.ID)
 		if err != nil {
 			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
+		if open > 0 {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("Не выполнено пунктов чек-листа: %d", open)})
+			return
+		}
 	}
-	if len(task.Repeat) > 0 {
-		// Update the task's date to the next occurrence based on the repeat pattern
-		now := time.Now()
-		next, err := NextDate(now, task.Date, task.Repeat)
+	// Completing a later occurrence than the current one only takes it out of the series,
+	// which still counts it toward the remaining occurrences of a limited series
+	if date := r.FormValue("date"); date != "" && date != task.Date {
+		o, err := findOccurrence(task, date)
+		if errors.Is(err, errOccurrenceNotFound) {
+			writeJson(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+			return
+		}
 		if err != nil {
-			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
-		err = db.UpdateDate(next, id)
+		now := time.Now()
+		c, err := completion(task, o.date, o.time, now)
+		if err == nil {
+			err = db.CompleteOccurrence(c)
+		}
 		if err != nil {
 			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
+		writeJson(w, http.StatusOK, map[string]any{})
+		return
+	}
+	if err := completeTask(task, time.Now()); err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
 	}
 	writeJson(w, http.StatusOK, map[string]any{})
 }
 
+// completeTask marks the current occurrence of a task done at now and records it in the history.
+// A one-off task or the last occurrence of a series is deleted; otherwise the task moves to its next occurrence.
+// The tasks that follow it are rescheduled in the same transaction.
+func completeTask(task *db.Task, now time.Time) error {
+	c, err := completion(task, task.Date, task.Time, now)
+	if err != nil {
+		return err
+	}
+	// If the task has no repeat or this was its last occurrence, delete it; otherwise, update the date
+	if len(task.Repeat) == 0 || task.Remaining == 1 {
+		return db.CompleteTask(c, "", "", 1, nil)
+	}
+	// Update the task's date to the next occurrence based on the repeat pattern
+	start, startTime := task.Date, task.Time
+	if task.Anchor == db.AnchorCompletion {
+		start, startTime, err = completionStart(task, now)
+		if err != nil {
+			return err
+		}
+	}
+	next, nextTime, err := NextDateTime(now, start, startTime, task.TimeZone, task.Repeat, task.Exdates...)
+	if errors.Is(err, recur.ErrSeriesEnded) {
+		// The rule has run out of occurrences, so the task is finished for good once missed ones are caught up on
+		next, nextTime, err = "", "", nil
+	}
+	if err != nil {
+		return err
+	}
+	next, nextTime, late, err := catchUp(task, now, next, nextTime)
+	if err != nil {
+		return err
+	}
+	// A limited series uses up every occurrence it moves past, not only the one that was done
+	used := 1
+	if next != "" && task.Remaining > 0 && task.Anchor == db.AnchorSchedule {
+		if used, err = passedOccurrences(task, occurrence{next, nextTime}); err != nil {
+			return err
+		}
+		if used >= task.Remaining {
+			next, nextTime = "", ""
+		}
+	}
+	return db.CompleteTask(c, next, nextTime, used, late)
+}
+
+// completion returns the completion at now of the occurrence of a task on the date and time of day,
+// timestamped in the task's time zone.
+func completion(task *db.Task, date string, tm string, now time.Time) (*db.Completion, error) {
+	loc, err := loadLocation(task.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewCompletion(task, date, tm, now.In(loc)), nil
+}
+
+// completionStart returns the date and time of day a completion-based task counts its next occurrence from:
+// today in the task's time zone, and for hourly, minutely and cron rules also the current time.
+func completionStart(task *db.Task, now time.Time) (string, string, error) {
+	loc, err := loadLocation(task.TimeZone)
+	if err != nil {
+		return "", "", err
+	}
+	rule, err := recur.Parse(task.Repeat)
+	if err != nil {
+		return "", "", err
+	}
+	now = now.In(loc)
+	if task.Time != "" && rule.HasTime() {
+		return now.Format(formatDate), now.Format(formatTime), nil
+	}
+	return now.Format(formatDate), task.Time, nil
+}
+
 func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if id == "" {
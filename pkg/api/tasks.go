@@ -1,39 +1,134 @@
 package api
 
 import (
+	"fmt"
 	"github.com/somepgs/go_final_project/pkg/db"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const limitTasks = 50 // limitTasks defines the maximum number of tasks to return in a single request.
 
+const (
+	defaultRange = 30  // defaultRange is the number of days after 'from' /api/tasks expands when 'to' is omitted
+	maxRange     = 366 // maxRange caps the number of days /api/tasks expands
+)
+
 type tasksResp struct {
-	Tasks []*db.Task `json:"tasks"`
+	Tasks     []*db.Task `json:"tasks"`
+	Truncated bool       `json:"truncated,omitempty"` // Truncated tells that the listing is cut short
 }
 
+// tasksHandler handles the /api/tasks endpoint.
+// Every repeating task is listed as its current occurrence with the occurrence's override applied.
+// With 'from' and 'to' (YYYYMMDD, inclusive) the tasks are expanded into all their occurrences in the range instead;
+// 'from' defaults to today and 'to' to defaultRange days after 'from'.
+// 'priority' keeps the tasks with the listed priorities, e.g. "1,2" or "P1,P2",
+// and 'tag' the tasks that have all of the listed tags, e.g. "дом,срочно".
+// Tasks are ordered by date, then priority, then time of day.
+// A range lists at most limitTasks occurrences of at most limitTasks tasks; a listing cut short
+// by either has "truncated": true.
+// Example request: /api/tasks?from=20240501&to=20240531&priority=1
 func tasksHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
 	search := r.FormValue("search")
 	if search != "" {
-		tasks, err := db.SearchTasks(search, limitTasks)
+		tasks, err := db.SearchTasks(search, filter, limitTasks)
+		if err == nil {
+			tasks, err = currentInstances(tasks)
+		}
 		if err != nil {
 			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
 		}
-		if len(tasks) == 0 {
-			writeJson(w, http.StatusOK, tasksResp{Tasks: []*db.Task{}})
-			return
-		}
 		writeJson(w, http.StatusOK, tasksResp{Tasks: tasks})
 		return
 	}
-	tasks, err := db.Tasks(limitTasks)
+	if r.FormValue("from") != "" || r.FormValue("to") != "" {
+		rangeTasksHandler(w, r, filter)
+		return
+	}
+	tasks, err := db.Tasks(filter, limitTasks)
+	if err == nil {
+		tasks, err = currentInstances(tasks)
+	}
 	if err != nil {
 		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
-	if len(tasks) == 0 {
-		writeJson(w, http.StatusOK, tasksResp{Tasks: []*db.Task{}})
+	writeJson(w, http.StatusOK, tasksResp{Tasks: tasks})
+}
+
+// rangeTasksHandler lists the occurrences of the tasks between the 'from' and 'to' dates.
+func rangeTasksHandler(w http.ResponseWriter, r *http.Request, filter db.Filter) {
+	from := time.Now()
+	if v := r.FormValue("from"); v != "" {
+		var err error
+		if from, err = time.Parse(formatDate, v); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты 'from', ожидается YYYYMMDD"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, defaultRange)
+	if v := r.FormValue("to"); v != "" {
+		var err error
+		if to, err = time.Parse(formatDate, v); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный формат даты 'to', ожидается YYYYMMDD"})
+			return
+		}
+	}
+	fromDate, toDate := from.Format(formatDate), to.Format(formatDate)
+	if toDate < fromDate || toDate > from.AddDate(0, 0, maxRange).Format(formatDate) {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Неверный диапазон дат: 'to' должна быть не раньше 'from' и не дальше 366 дней от неё"})
+		return
+	}
+
+	// One task more than the listing holds tells whether some of them are left out
+	tasks, err := db.TasksUntil(toDate, filter, limitTasks+1)
+	truncated := len(tasks) > limitTasks
+	if err == nil {
+		tasks, err = expandInstances(tasks[:min(len(tasks), limitTasks)], fromDate, toDate)
+	}
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 		return
 	}
-	writeJson(w, http.StatusOK, tasksResp{Tasks: tasks})
+	if len(tasks) > limitTasks {
+		tasks, truncated = tasks[:limitTasks], true
+	}
+	writeJson(w, http.StatusOK, tasksResp{Tasks: tasks, Truncated: truncated})
+}
+
+// parseFilter reads the filter of the task listing from the request.
+func parseFilter(r *http.Request) (db.Filter, error) {
+	var f db.Filter
+	if v := r.FormValue("priority"); v != "" {
+		for _, p := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(p), "P"))
+			if err != nil || n < db.PriorityUrgent || n > db.PriorityNone {
+				return f, fmt.Errorf("Неверный приоритет, ожидается от %d до %d: %s", db.PriorityUrgent, db.PriorityNone, p)
+			}
+			f.Priorities = append(f.Priorities, n)
+		}
+	}
+	if v := r.FormValue("tag"); v != "" {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				f.Tags = append(f.Tags, tag)
+			}
+		}
+	}
+	if v := strings.TrimSpace(r.FormValue("project")); v != "" {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return f, fmt.Errorf("Неверный ID проекта: %s", v)
+		}
+		f.Project = v
+	}
+	return f, nil
 }
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+const formatTime = "15:04" // Format for time of day in HH:MM format
+
+// loadLocation returns the IANA time zone named tz, or the server's zone if tz is empty.
+func loadLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %s", tz)
+	}
+	return loc, nil
+}
+
+// checkTime validates a time of day in HH:MM format.
+func checkTime(tm string) error {
+	if _, err := time.Parse(formatTime, tm); err != nil || len(tm) != len(formatTime) {
+		return fmt.Errorf("invalid time, expected HH:MM: %s", tm)
+	}
+	return nil
+}
+
+// taskStart returns the moment the task is scheduled for in its time zone,
+// the start of the day for tasks without a time of day.
+func taskStart(task *db.Task) (time.Time, error) {
+	loc, err := loadLocation(task.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	tm := task.Time
+	if tm == "" {
+		tm = "00:00"
+	}
+	return time.ParseInLocation(formatDate+" "+formatTime, task.Date+" "+tm, loc)
+}
+
+// NextDateTime calculates the next date and time of day of a task that starts at dstart and tstart
+// in the time zone tz. Tasks without a time of day are handled by NextDate.
+// Rules with a day granularity keep the time of day and still allow today while its time has not come;
+// hourly ("h 2"), minutely ("min 30") and cron rules move the time of day as well.
+func NextDateTime(now time.Time, dstart, tstart, tz, repeat string, exclude ...string) (string, string, error) {
+	if tstart == "" {
+		next, err := NextDate(now, dstart, repeat, exclude...)
+		return next, "", err
+	}
+	if dstart == "" {
+		return "", "", fmt.Errorf("data cannot be empty")
+	}
+	if repeat == "" {
+		return "", "", fmt.Errorf("repeat cannot be empty")
+	}
+	if err := checkTime(tstart); err != nil {
+		return "", "", err
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return "", "", err
+	}
+	start, err := time.ParseInLocation(formatDate+" "+formatTime, dstart+" "+tstart, loc)
+	if err != nil {
+		return "", "", err
+	}
+	now = now.In(loc)
+
+	rule, err := recur.Parse(repeat)
+	if err != nil {
+		return "", "", err
+	}
+	if rule.HasTime() {
+		rule.Start = start
+		// Every excluded moment moves now forward, so the loop ends
+		for {
+			next, err := rule.NextTime(now)
+			if err != nil {
+				return "", "", err
+			}
+			if !slices.Contains(exclude, next.Format(formatDate)) {
+				return next.Format(formatDate), next.Format(formatTime), nil
+			}
+			now = next
+		}
+	}
+
+	if now.Format(formatTime) < tstart {
+		now = now.AddDate(0, 0, -1) // Today's occurrence is still ahead
+	}
+	next, err := NextDate(now, dstart, repeat, exclude...)
+	return next, tstart, err
+}
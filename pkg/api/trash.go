@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+// trashHandler handles the /api/trash endpoint.
+// GET lists the deleted tasks, the most recently deleted first; DELETE purges the task 'id' for good,
+// or every task in the trash if 'id' is omitted.
+// Example response to DELETE: {"purged":3}
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tasks, err := db.TrashedTasks(limitTasks)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if tasks == nil {
+			tasks = []*db.Task{}
+		}
+		writeJson(w, http.StatusOK, tasksResp{Tasks: tasks})
+	case http.MethodDelete:
+		if id := r.FormValue("id"); id != "" {
+			if err := db.PurgeTask(id); err != nil {
+				writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			writeJson(w, http.StatusOK, map[string]any{"purged": 1})
+			return
+		}
+		purged, err := db.PurgeTrash(time.Time{})
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]any{"purged": purged})
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+	}
+}
+
+// restoreTaskHandler handles the /api/trash/restore endpoint. POST takes the task 'id' out of the trash.
+func restoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "Метод не поддерживается"})
+		return
+	}
+	id := r.FormValue("id")
+	if id == "" {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": "Не указан ID задачи"})
+		return
+	}
+	if err := db.RestoreTask(id); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]any{})
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Attachment describes a file attached to a task. The content of the file is stored in the database along with it.
+type Attachment struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Name        string `json:"name"`         // Name is the file name the attachment was uploaded with
+	ContentType string `json:"content_type"` // ContentType is the MIME type of the content
+	Size        int64  `json:"size"`         // Size is the size of the content in bytes
+	CreatedAt   string `json:"created_at"`   // CreatedAt is the moment the attachment was uploaded in RFC 3339 format, UTC
+}
+
+// Attachments returns the attachments of a task by its ID in the order they were uploaded, without their content.
+func Attachments(id string) ([]*Attachment, error) {
+	rows, err := db.Query(`SELECT id, task_id, name, content_type, size, created_at FROM attachments
+		WHERE task_id = :id ORDER BY id`, sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.Name, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// AddAttachment stores the content as an attachment of the task a.TaskID and returns the ID of the attachment.
+// The size and upload time of the attachment are set from the content and the current time.
+func AddAttachment(a *Attachment, data []byte) (int64, error) {
+	a.Size = int64(len(data))
+	a.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	query := `INSERT INTO attachments (task_id, name, content_type, size, created_at, data)
+		SELECT id, :name, :content_type, :size, :created_at, :data FROM scheduler WHERE id = :id AND deleted_at = ''`
+	res, err := db.Exec(query, sql.Named("id", a.TaskID), sql.Named("name", a.Name), sql.Named("content_type", a.ContentType),
+		sql.Named("size", a.Size), sql.Named("created_at", a.CreatedAt), sql.Named("data", data))
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf(`incorrect id for adding attachment`)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	a.ID = fmt.Sprint(id)
+	return id, nil
+}
+
+// GetAttachment retrieves an attachment of the task 'id' with its content; it returns nil if there is no such attachment.
+func GetAttachment(id string, attachment string) (*Attachment, []byte, error) {
+	var (
+		a    Attachment
+		data []byte
+	)
+	err := db.QueryRow(`SELECT id, task_id, name, content_type, size, created_at, data FROM attachments
+		WHERE id = :attachment AND task_id = :id`, sql.Named("id", id), sql.Named("attachment", attachment)).
+		Scan(&a.ID, &a.TaskID, &a.Name, &a.ContentType, &a.Size, &a.CreatedAt, &data)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &a, data, nil
+}
+
+// DeleteAttachment removes an attachment of the task 'id'.
+func DeleteAttachment(id string, attachment string) error {
+	res, err := db.Exec(`DELETE FROM attachments WHERE id = :attachment AND task_id = :id`,
+		sql.Named("id", id), sql.Named("attachment", attachment))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for deleting attachment`)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrItemNotFound is returned when a checklist item to change does not belong to the task.
+var ErrItemNotFound = errors.New("checklist item not found")
+
+// ChecklistItem is a step of a task. Items are checked off for the current occurrence of a repeating task.
+type ChecklistItem struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"` // Position orders the items of the checklist, lower first
+}
+
+// Checklist returns the items of a task by its ID in their order.
+func Checklist(id string) ([]*ChecklistItem, error) {
+	rows, err := db.Query(`SELECT id, text, done, position FROM checklist WHERE task_id = :id ORDER BY position, id`,
+		sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*ChecklistItem{}
+	for rows.Next() {
+		var item ChecklistItem
+		if err := rows.Scan(&item.ID, &item.Text, &item.Done, &item.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AddChecklistItem appends an unchecked item to the checklist of a task by its ID and returns the ID of the item.
+func AddChecklistItem(id string, text string) (int64, error) {
+	query := `INSERT INTO checklist (task_id, position, text)
+		SELECT id, (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist WHERE task_id = :id), :text
+		FROM scheduler WHERE id = :id AND deleted_at = ''`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("text", text))
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf(`incorrect id for adding checklist item`)
+	}
+	return res.LastInsertId()
+}
+
+// UpdateChecklistItem changes the text and the done flag of an item of the task 'id'.
+func UpdateChecklistItem(id string, item *ChecklistItem) error {
+	query := `UPDATE checklist SET text = :text, done = :done WHERE id = :item AND task_id = :id`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("item", item.ID),
+		sql.Named("text", item.Text), sql.Named("done", item.Done))
+	if err != nil {
+		return err
+	}
+	return itemAffected(res)
+}
+
+// ToggleChecklistItem checks an unchecked item of the task 'id' or unchecks a checked one,
+// and returns whether the item is checked now.
+func ToggleChecklistItem(id string, item string) (bool, error) {
+	var done bool
+	err := inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE checklist SET done = 1 - done WHERE id = :item AND task_id = :id`,
+			sql.Named("id", id), sql.Named("item", item))
+		if err != nil {
+			return err
+		}
+		if err := itemAffected(res); err != nil {
+			return err
+		}
+		return tx.QueryRow(`SELECT done FROM checklist WHERE id = :item`, sql.Named("item", item)).Scan(&done)
+	})
+	return done, err
+}
+
+// ReorderChecklist puts the items of the task 'id' in the order of their IDs, which must list every item once.
+func ReorderChecklist(id string, items []string) error {
+	return inTx(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT count(*) FROM checklist WHERE task_id = :id`, sql.Named("id", id)).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count != len(items) {
+			return fmt.Errorf("expected all %d checklist items, got %d", count, len(items))
+		}
+		for i, item := range items {
+			res, err := tx.Exec(`UPDATE checklist SET position = :position WHERE id = :item AND task_id = :id AND position >= 0`,
+				sql.Named("id", id), sql.Named("item", item), sql.Named("position", -1-i))
+			if err != nil {
+				return err
+			}
+			// An item listed twice has a negative position already
+			if err := itemAffected(res); err != nil {
+				return fmt.Errorf("%w: %s", err, item)
+			}
+		}
+		_, err = tx.Exec(`UPDATE checklist SET position = -position WHERE task_id = :id`, sql.Named("id", id))
+		return err
+	})
+}
+
+// DeleteChecklistItem removes an item from the checklist of the task 'id'.
+func DeleteChecklistItem(id string, item string) error {
+	res, err := db.Exec(`DELETE FROM checklist WHERE id = :item AND task_id = :id`,
+		sql.Named("id", id), sql.Named("item", item))
+	if err != nil {
+		return err
+	}
+	return itemAffected(res)
+}
+
+// itemAffected returns ErrItemNotFound if the statement changed no checklist item.
+func itemAffected(res sql.Result) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
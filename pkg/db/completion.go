@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Completion records an occurrence of a task that was done.
+type Completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`        // Title is the title of the task when it was done
+	Date        string `json:"date"`         // Date is the date in YYYYMMDD format the occurrence was scheduled on
+	Time        string `json:"time"`         // Time is the time of day the occurrence was scheduled at; empty for whole-day tasks
+	CompletedAt string `json:"completed_at"` // CompletedAt is the moment the occurrence was done in RFC 3339 format
+}
+
+// NewCompletion returns the completion of the occurrence of a task on the date and time of day at the moment 'at',
+// which is kept in its own time zone.
+func NewCompletion(task *Task, date string, tm string, at time.Time) *Completion {
+	return &Completion{TaskID: task.ID, Title: task.Title, Date: date, Time: tm, CompletedAt: at.Format(time.RFC3339)}
+}
+
+// CompleteTask records the completion of the current occurrence of a task and, in the same transaction,
+// reschedules the tasks that follow it and moves the task to its next date and time of day,
+// or removes it for good if next is empty.
+// used is the number of occurrences the task moves past, which a limited series uses up,
+// and the tasks, such as missed occurrences to catch up on, are added along with it.
+func CompleteTask(c *Completion, next string, nextTime string, used int, tasks []*Task) error {
+	return inTx(func(tx *sql.Tx) error {
+		if err := addCompletion(tx, c); err != nil {
+			return err
+		}
+		// The tasks that follow this one are rescheduled before a finished task takes its dependencies with it
+		if err := moveDependents(tx, c.TaskID, c.day()); err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if _, err := addTask(tx, task); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return purgeTask(tx, c.TaskID)
+		}
+		return updateDate(tx, next, nextTime, used, c.TaskID)
+	})
+}
+
+// CompleteOccurrence records the completion of a later occurrence of a repeating task and, in the same transaction,
+// reschedules the tasks that follow it and takes the occurrence out of the series.
+func CompleteOccurrence(c *Completion) error {
+	return inTx(func(tx *sql.Tx) error {
+		if err := addCompletion(tx, c); err != nil {
+			return err
+		}
+		if err := moveDependents(tx, c.TaskID, c.day()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT OR IGNORE INTO exdates (task_id, date) VALUES (:id, :date)`,
+			sql.Named("id", c.TaskID), sql.Named("date", c.Date))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM overrides WHERE task_id = :id AND date = :date`,
+			sql.Named("id", c.TaskID), sql.Named("date", c.Date))
+		return err
+	})
+}
+
+// day returns the day (YYYYMMDD) the task was done on in the time zone of CompletedAt,
+// which addCompletion has checked to be in RFC 3339 format.
+func (c *Completion) day() string {
+	at, _ := time.Parse(time.RFC3339, c.CompletedAt)
+	return at.Format("20060102")
+}
+
+// addCompletion inserts a completion within the transaction.
+// Its day is taken in the time zone of CompletedAt, so the history is filtered by the day the task was done on.
+func addCompletion(tx *sql.Tx, c *Completion) error {
+	if _, err := time.Parse(time.RFC3339, c.CompletedAt); err != nil {
+		return err
+	}
+	query := `INSERT INTO completions (task_id, title, date, time, completed_at, completed_on)
+		VALUES (:task_id, :title, :date, :time, :completed_at, :completed_on)`
+	_, err := tx.Exec(query, sql.Named("task_id", c.TaskID), sql.Named("title", c.Title), sql.Named("date", c.Date),
+		sql.Named("time", c.Time), sql.Named("completed_at", c.CompletedAt), sql.Named("completed_on", c.day()))
+	return err
+}
+
+// History returns up to limit completions done from one date to another (YYYYMMDD, both included), the latest first.
+// Empty dates leave the range open, and a non-empty task ID keeps the completions of that task only.
+func History(from string, to string, task string, limit int) ([]*Completion, error) {
+	query := `SELECT id, task_id, title, date, time, completed_at FROM completions
+		WHERE (:from = '' OR completed_on >= :from) AND (:to = '' OR completed_on <= :to) AND (:task = '' OR task_id = :task)
+		ORDER BY completed_on DESC, completed_at DESC, id DESC LIMIT :limit`
+	rows, err := db.Query(query, sql.Named("from", from), sql.Named("to", to), sql.Named("task", task),
+		sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*Completion{}
+	for rows.Next() {
+		var c Completion
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.Title, &c.Date, &c.Time, &c.CompletedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
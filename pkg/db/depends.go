@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxDependencyDays = 399 // maxDependencyDays caps the number of days a task may follow its predecessor by
+
+// Dependency ties the date of a task to the completion of another one: "after 12 +3d".
+type Dependency struct {
+	TaskID    string // TaskID is the ID of the dependent task
+	DependsOn string // DependsOn is the ID of the task it follows
+	Days      int    // Days is the number of days after the completion of the predecessor the task is due
+}
+
+// ParseDependency parses a relative date spec "after <id> [+<n>d|+<n>w]" of a task.
+func ParseDependency(spec string) (Dependency, error) {
+	var dep Dependency
+	arr := strings.Fields(spec)
+	if len(arr) < 2 || len(arr) > 3 || arr[0] != "after" {
+		return dep, fmt.Errorf("invalid dependency, expected after <id> +<n>d: %s", spec)
+	}
+	if id, err := strconv.ParseInt(arr[1], 10, 64); err != nil || id <= 0 {
+		return dep, fmt.Errorf("invalid task id in dependency: %s", arr[1])
+	}
+	dep.DependsOn = arr[1]
+	if len(arr) == 3 {
+		offset := arr[2]
+		unit := 1
+		switch {
+		case strings.HasSuffix(offset, "d"):
+		case strings.HasSuffix(offset, "w"):
+			unit = 7
+		default:
+			return dep, fmt.Errorf("invalid offset in dependency, expected +<n>d or +<n>w: %s", offset)
+		}
+		n, err := strconv.Atoi(offset[:len(offset)-1])
+		if err != nil || !strings.HasPrefix(offset, "+") || n*unit > maxDependencyDays {
+			return dep, fmt.Errorf("invalid offset in dependency, expected +0d to +%dd: %s", maxDependencyDays, offset)
+		}
+		dep.Days = n * unit
+	}
+	return dep, nil
+}
+
+// String returns the canonical spec of the dependency, e.g. "after 12 +3d".
+func (d Dependency) String() string {
+	return fmt.Sprintf("after %s +%dd", d.DependsOn, d.Days)
+}
+
+// setDependency replaces the dependency of a task within the transaction; an empty spec removes it.
+func setDependency(tx *sql.Tx, id any, spec string) error {
+	_, err := tx.Exec(`DELETE FROM dependencies WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil || spec == "" {
+		return err
+	}
+	dep, err := ParseDependency(spec)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO dependencies (task_id, depends_on, days) VALUES (:id, :depends_on, :days)`,
+		sql.Named("id", id), sql.Named("depends_on", dep.DependsOn), sql.Named("days", dep.Days))
+	return err
+}
+
+// moveDependents reschedules the tasks that follow the task by its ID done on the date (YYYYMMDD) within the
+// transaction to the number of days after it they wait, and then the tasks that follow them in turn.
+func moveDependents(tx *sql.Tx, id string, done string) error {
+	deps, err := dependents(tx, id)
+	if err != nil {
+		return err
+	}
+	day, err := time.Parse("20060102", done)
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		date := day.AddDate(0, 0, dep.Days).Format("20060102")
+		if err := moveTask(tx, dep.TaskID, date); err != nil {
+			return err
+		}
+		// The chain has no cycles, so the recursion ends
+		if err := moveDependents(tx, dep.TaskID, date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependents returns the dependencies of the tasks that follow the task by its ID within the transaction,
+// leaving out the tasks in the trash.
+func dependents(tx *sql.Tx, id string) ([]Dependency, error) {
+	rows, err := tx.Query(`SELECT task_id, depends_on, days FROM dependencies WHERE depends_on = :id
+		AND task_id IN (SELECT id FROM scheduler WHERE deleted_at = '') ORDER BY task_id`,
+		sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []Dependency
+	for rows.Next() {
+		var d Dependency
+		if err := rows.Scan(&d.TaskID, &d.DependsOn, &d.Days); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// moveTask sets the date of a task by its ID within the transaction without using up an occurrence,
+// as when its predecessor is done.
+func moveTask(tx *sql.Tx, id string, date string) error {
+	res, err := tx.Exec(`UPDATE scheduler SET date = :date WHERE id = :id AND deleted_at = ''`, sql.Named("date", date), sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for moving task`)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AddExdate excludes a date from the occurrences of a task by its ID.
+func AddExdate(id string, date string) error {
+	query := `INSERT OR IGNORE INTO exdates (task_id, date) SELECT id, :date FROM scheduler WHERE id = :id AND deleted_at = ''`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("date", date))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		// Either the task does not exist or the date is already excluded
+		task, err := GetTask(id)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return fmt.Errorf(`incorrect id for excluding date`)
+		}
+	}
+	return nil
+}
+
+// SkipDate excludes the current date of a repeating task by its ID and, in the same transaction, moves the task
+// to its next occurrence, using up used occurrences of a limited series.
+func SkipDate(id, date, next, nextTime string, used int) error {
+	return inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO exdates (task_id, date) VALUES (:id, :date)`,
+			sql.Named("id", id), sql.Named("date", date))
+		if err != nil {
+			return err
+		}
+		return updateDate(tx, next, nextTime, used, id)
+	})
+}
+
+// DeleteExdate removes an excluded date from a task by its ID.
+func DeleteExdate(id string, date string) error {
+	query := `DELETE FROM exdates WHERE task_id = :id AND date = :date`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("date", date))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`date is not excluded for the task`)
+	}
+	return nil
+}
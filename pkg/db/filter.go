@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Filter narrows down the tasks a listing returns; an empty field does not filter.
+type Filter struct {
+	Priorities []int    // Priorities keeps the tasks with one of the priorities
+	Tags       []string // Tags keeps the tasks that have all of the tags
+	Project    string   // Project keeps the tasks of the project by its ID, "0" the tasks without a project
+}
+
+// conditions returns the SQL conditions of the filter and their named arguments.
+func (f Filter) conditions() ([]string, []any) {
+	var conds []string
+	var args []any
+	if len(f.Priorities) > 0 {
+		names := make([]string, len(f.Priorities))
+		for i, p := range f.Priorities {
+			names[i] = fmt.Sprintf(":priority%d", i)
+			args = append(args, sql.Named(fmt.Sprintf("priority%d", i), p))
+		}
+		conds = append(conds, "priority IN ("+strings.Join(names, ", ")+")")
+	}
+	for i, tag := range f.Tags {
+		name := fmt.Sprintf("tag%d", i)
+		conds = append(conds, "id IN (SELECT task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.key = :"+name+")")
+		args = append(args, sql.Named(name, tagKey(tag)))
+	}
+	if f.Project != "" {
+		conds = append(conds, "project_id = :project")
+		args = append(args, sql.Named("project", f.Project))
+	}
+	return conds, args
+}
+
+// queryTasks retrieves a limited number of tasks that meet the condition, if any, and the filter,
+// ordered by date, priority and time of day. Tasks in the trash are left out.
+func queryTasks(cond string, f Filter, limit int, args ...any) ([]*Task, error) {
+	conds, filterArgs := f.conditions()
+	if cond != "" {
+		conds = append([]string{"(" + cond + ")"}, conds...)
+	}
+	conds = append([]string{"deleted_at = ''"}, conds...)
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE " + strings.Join(conds, " AND ")
+	query += " ORDER BY date, priority, time LIMIT :limit"
+
+	args = append(append(args, filterArgs...), sql.Named("limit", limit))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getTasks(rows)
+}
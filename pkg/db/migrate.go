@@ -0,0 +1,392 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// migration is a versioned change of the schema with the way back.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+	down    func(tx *sql.Tx) error
+}
+
+// column is a column added by a migration.
+type column struct {
+	name string
+	def  string
+}
+
+// migrations lists the schema changes in the order they are applied.
+// The changes that predate the migrations are written so that they also apply to databases
+// that already have them, which lets those databases adopt the migrations.
+// A released migration is never edited: a new change gets a new version.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create scheduler",
+		up: execAll(`CREATE TABLE IF NOT EXISTS scheduler (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date CHAR(8) NOT NULL DEFAULT "",
+			title VARCHAR(128) NOT NULL DEFAULT "",
+			comment TEXT NOT NULL DEFAULT "",
+			repeat VARCHAR(128) NOT NULL DEFAULT ""
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler (date)`),
+		down: execAll(`DROP TABLE scheduler`),
+	},
+	{
+		version: 2,
+		name:    "end conditions",
+		up: addColumns("scheduler",
+			column{"remaining", `INTEGER NOT NULL DEFAULT 0`},
+			column{"end_date", `CHAR(8) NOT NULL DEFAULT ""`}),
+		down: dropColumns("scheduler", "remaining", "end_date"),
+	},
+	{
+		version: 3,
+		name:    "excluded dates",
+		up: execAll(`CREATE TABLE IF NOT EXISTS exdates (
+			task_id INTEGER NOT NULL,
+			date CHAR(8) NOT NULL,
+			PRIMARY KEY (task_id, date)
+			)`),
+		down: execAll(`DROP TABLE exdates`),
+	},
+	{
+		version: 4,
+		name:    "time of day",
+		up: addColumns("scheduler",
+			column{"time", `CHAR(5) NOT NULL DEFAULT ""`},
+			column{"timezone", `VARCHAR(64) NOT NULL DEFAULT ""`}),
+		down: dropColumns("scheduler", "time", "timezone"),
+	},
+	{
+		version: 5,
+		name:    "repeat anchor",
+		up:      addColumns("scheduler", column{"anchor", `VARCHAR(16) NOT NULL DEFAULT "schedule"`}),
+		down:    dropColumns("scheduler", "anchor"),
+	},
+	{
+		version: 6,
+		name:    "catch-up policy",
+		up:      addColumns("scheduler", column{"catchup", `VARCHAR(16) NOT NULL DEFAULT "skip"`}),
+		down:    dropColumns("scheduler", "catchup"),
+	},
+	{
+		version: 7,
+		name:    "occurrence overrides",
+		up: execAll(`CREATE TABLE IF NOT EXISTS overrides (
+			task_id INTEGER NOT NULL,
+			date CHAR(8) NOT NULL,
+			new_date CHAR(8) NOT NULL DEFAULT "",
+			time CHAR(5) NOT NULL DEFAULT "",
+			comment TEXT NOT NULL DEFAULT "",
+			PRIMARY KEY (task_id, date)
+			)`),
+		down: execAll(`DROP TABLE overrides`),
+	},
+	{
+		version: 8,
+		name:    "dependencies",
+		up: execAll(`CREATE TABLE IF NOT EXISTS dependencies (
+			task_id INTEGER PRIMARY KEY,
+			depends_on INTEGER NOT NULL,
+			days INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_dependencies_depends_on ON dependencies (depends_on)`),
+		down: execAll(`DROP TABLE dependencies`),
+	},
+	{
+		version: 9,
+		name:    "priority",
+		up:      addColumns("scheduler", column{"priority", `INTEGER NOT NULL DEFAULT 4`}),
+		down:    dropColumns("scheduler", "priority"),
+	},
+	{
+		version: 10,
+		name:    "tags",
+		up: execAll(`CREATE TABLE IF NOT EXISTS tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(64) NOT NULL,
+			key VARCHAR(64) NOT NULL UNIQUE
+			)`,
+			`CREATE TABLE IF NOT EXISTS task_tags (
+			task_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (task_id, tag_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags (tag_id)`),
+		down: execAll(`DROP TABLE task_tags`, `DROP TABLE tags`),
+	},
+	{
+		version: 11,
+		name:    "projects",
+		up: steps(
+			execAll(`CREATE TABLE IF NOT EXISTS projects (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(128) NOT NULL DEFAULT "",
+			color CHAR(7) NOT NULL DEFAULT "",
+			archived INTEGER NOT NULL DEFAULT 0,
+			position INTEGER NOT NULL DEFAULT 0
+			)`),
+			addColumns("scheduler", column{"project_id", `INTEGER NOT NULL DEFAULT 0`}),
+			execAll(`CREATE INDEX IF NOT EXISTS idx_scheduler_project_id ON scheduler (project_id)`)),
+		down: steps(
+			execAll(`DROP INDEX idx_scheduler_project_id`),
+			dropColumns("scheduler", "project_id"),
+			execAll(`DROP TABLE projects`)),
+	},
+	{
+		version: 12,
+		name:    "checklists",
+		up: steps(
+			execAll(`CREATE TABLE IF NOT EXISTS checklist (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			text VARCHAR(256) NOT NULL DEFAULT "",
+			done INTEGER NOT NULL DEFAULT 0
+			)`,
+				`CREATE INDEX IF NOT EXISTS idx_checklist_task_id ON checklist (task_id, position)`),
+			addColumns("scheduler", column{"checklist_mode", `VARCHAR(16) NOT NULL DEFAULT "free"`})),
+		down: steps(
+			dropColumns("scheduler", "checklist_mode"),
+			execAll(`DROP TABLE checklist`)),
+	},
+	{
+		version: 13,
+		name:    "completions",
+		up: execAll(`CREATE TABLE IF NOT EXISTS completions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			title VARCHAR(256) NOT NULL DEFAULT "",
+			date CHAR(8) NOT NULL DEFAULT "",
+			time CHAR(5) NOT NULL DEFAULT "",
+			completed_at VARCHAR(32) NOT NULL DEFAULT "",
+			completed_on CHAR(8) NOT NULL DEFAULT ""
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_completions_completed_on ON completions (completed_on)`),
+		down: execAll(`DROP TABLE completions`),
+	},
+	{
+		version: 14,
+		name:    "trash",
+		up: steps(
+			addColumns("scheduler", column{"deleted_at", `VARCHAR(32) NOT NULL DEFAULT ""`}),
+			execAll(`CREATE INDEX IF NOT EXISTS idx_scheduler_deleted_at ON scheduler (deleted_at)`)),
+		down: steps(
+			execAll(`DROP INDEX idx_scheduler_deleted_at`),
+			dropColumns("scheduler", "deleted_at")),
+	},
+	{
+		version: 15,
+		name:    "attachments",
+		up: execAll(`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL DEFAULT "",
+			content_type VARCHAR(255) NOT NULL DEFAULT "",
+			size INTEGER NOT NULL DEFAULT 0,
+			created_at VARCHAR(32) NOT NULL DEFAULT "",
+			data BLOB NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_attachments_task_id ON attachments (task_id)`),
+		down: execAll(`DROP TABLE attachments`),
+	},
+}
+
+// MigrationStatus describes a migration and whether the database has it.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt string // AppliedAt is the UTC time the migration was applied at; empty if it was not
+}
+
+// LatestVersion returns the version of the schema the code expects.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Version returns the version of the schema of the database, 0 for an empty database.
+func Version() (int, error) {
+	if err := createMigrationsTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Migrate applies or reverts migrations until the schema of the database is at the given version.
+// Every migration runs in its own transaction, so a failed one leaves the database at the previous version.
+func Migrate(target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("unknown schema version %d, expected 0 to %d", target, LatestVersion())
+	}
+	current, err := Version()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version > current && m.version <= target {
+			err := inTx(func(tx *sql.Tx) error {
+				if err := m.up(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+	}
+	for _, m := range slices.Backward(migrations) {
+		if m.version <= current && m.version > target {
+			err := inTx(func(tx *sql.Tx) error {
+				if err := m.down(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Migrations returns every known migration with the time it was applied to the database.
+func Migrations() ([]MigrationStatus, error) {
+	if err := createMigrationsTable(); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		res[i] = MigrationStatus{Version: m.version, Name: m.name, AppliedAt: applied[m.version]}
+	}
+	return res, nil
+}
+
+// createMigrationsTable creates the table that records the applied migrations.
+func createMigrationsTable() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(128) NOT NULL DEFAULT "",
+		applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	return err
+}
+
+// inTx runs fn in a transaction that is committed if fn succeeds.
+func inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execAll returns a migration step that executes the statements in order.
+func execAll(queries ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, q := range queries {
+			if _, err := tx.Exec(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// steps returns a migration step that runs the steps in order.
+func steps(fns ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, fn := range fns {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumns returns a migration step that adds the columns the table does not have yet.
+func addColumns(table string, cols ...column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		existing, err := tableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		for _, col := range cols {
+			if existing[col.name] {
+				continue
+			}
+			if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + col.name + " " + col.def); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropColumns returns a migration step that removes the columns from the table.
+func dropColumns(table string, names ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, name := range names {
+			if _, err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN " + name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// tableColumns returns the set of the column names of the table.
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Override changes a single occurrence of a repeating task without altering the series.
+// Empty fields keep the values of the series.
+type Override struct {
+	Date    string `json:"date"`     // Date is the date in YYYYMMDD format the series schedules the occurrence on
+	NewDate string `json:"new_date"` // NewDate is the date in YYYYMMDD format the occurrence is moved to
+	Time    string `json:"time"`     // Time is the time of day in HH:MM format the occurrence is moved to
+	Comment string `json:"comment"`  // Comment replaces the comment of the series for the occurrence
+}
+
+// Overrides returns the occurrence overrides of a task by its ID, ordered by the scheduled date.
+func Overrides(id string) ([]Override, error) {
+	rows, err := db.Query(`SELECT date, new_date, time, comment FROM overrides WHERE task_id = :id ORDER BY date`,
+		sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []Override
+	for rows.Next() {
+		var o Override
+		if err := rows.Scan(&o.Date, &o.NewDate, &o.Time, &o.Comment); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// SetOverride stores the override of an occurrence of a task by its ID, replacing the previous one.
+func SetOverride(id string, o Override) error {
+	query := `INSERT OR REPLACE INTO overrides (task_id, date, new_date, time, comment)
+		SELECT id, :date, :new_date, :time, :comment FROM scheduler WHERE id = :id AND deleted_at = ''`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("date", o.Date), sql.Named("new_date", o.NewDate),
+		sql.Named("time", o.Time), sql.Named("comment", o.Comment))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for overriding occurrence`)
+	}
+	return nil
+}
+
+// DeleteOverride removes the override of an occurrence of a task by its ID; a missing override is not an error.
+func DeleteOverride(id string, date string) error {
+	_, err := db.Exec(`DELETE FROM overrides WHERE task_id = :id AND date = :date`,
+		sql.Named("id", id), sql.Named("date", date))
+	return err
+}
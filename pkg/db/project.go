@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Project groups tasks, e.g. the tasks of one initiative.
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`    // Color is the color of the project in #RRGGBB format; empty for the default one
+	Archived bool   `json:"archived"` // Archived projects are left out of the project list unless asked for
+	Position int    `json:"position"` // Position orders the projects in the list, lower first
+}
+
+// Projects returns the projects ordered by position, including the archived ones if asked to.
+func Projects(archived bool) ([]*Project, error) {
+	rows, err := db.Query(`SELECT id, name, color, archived, position FROM projects
+		WHERE archived = 0 OR :archived ORDER BY archived, position, id`, sql.Named("archived", archived))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []*Project{}
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Color, &p.Archived, &p.Position); err != nil {
+			return nil, err
+		}
+		projects = append(projects, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// GetProject retrieves a project by its ID; it returns nil if there is no such project.
+func GetProject(id string) (*Project, error) {
+	var p Project
+	err := db.QueryRow(`SELECT id, name, color, archived, position FROM projects WHERE id = :id`, sql.Named("id", id)).
+		Scan(&p.ID, &p.Name, &p.Color, &p.Archived, &p.Position)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AddProject inserts a new project and returns its ID. A project without a position goes to the end of the list.
+func AddProject(p *Project) (int64, error) {
+	query := `INSERT INTO projects (name, color, archived, position)
+		VALUES (:name, :color, :archived, CASE WHEN :position > 0 THEN :position
+			ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM projects) END)`
+	res, err := db.Exec(query, sql.Named("name", p.Name), sql.Named("color", p.Color),
+		sql.Named("archived", p.Archived), sql.Named("position", p.Position))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateProject updates an existing project.
+func UpdateProject(p *Project) error {
+	query := `UPDATE projects SET name = :name, color = :color, archived = :archived, position = :position WHERE id = :id`
+	res, err := db.Exec(query, sql.Named("id", p.ID), sql.Named("name", p.Name), sql.Named("color", p.Color),
+		sql.Named("archived", p.Archived), sql.Named("position", p.Position))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for updating project`)
+	}
+	return nil
+}
+
+// DeleteProject removes a project by its ID; its tasks are kept without a project.
+func DeleteProject(id string) error {
+	return inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM projects WHERE id = :id`, sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf(`incorrect id for deleting project`)
+		}
+		_, err = tx.Exec(`UPDATE scheduler SET project_id = 0 WHERE project_id = :id`, sql.Named("id", id))
+		return err
+	})
+}
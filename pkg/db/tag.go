@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTagNotFound is returned when a tag to change does not exist.
+var ErrTagNotFound = errors.New("tag not found")
+
+// Tag is a label tasks are grouped by, such as "home" or "errands".
+// Names are unique regardless of case: a tag is looked up by its key, the lower-case name.
+type Tag struct {
+	Name  string `json:"name"`
+	Tasks int    `json:"tasks"` // Tasks is the number of tasks with the tag
+}
+
+// setTags replaces the tags of a task within the transaction, creating the tags that do not exist yet.
+func setTags(tx *sql.Tx, id any, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	for _, name := range tags {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO tags (name, key) VALUES (:name, :key)`,
+			sql.Named("name", name), sql.Named("key", tagKey(name)))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) SELECT :id, id FROM tags WHERE key = :key`,
+			sql.Named("id", id), sql.Named("key", tagKey(name)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tags returns all tags with the number of tasks of each, ordered by name.
+func Tags() ([]Tag, error) {
+	rows, err := db.Query(`SELECT name, (SELECT count(*) FROM task_tags JOIN scheduler ON scheduler.id = task_tags.task_id
+		WHERE tag_id = tags.id AND deleted_at = '') FROM tags ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Tasks); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// RenameTag gives a tag a new name, which no other tag may have; see MergeTags for joining tags.
+func RenameTag(name, newName string) error {
+	return inTx(func(tx *sql.Tx) error {
+		id, err := tagID(tx, name)
+		if err != nil {
+			return err
+		}
+		other, err := tagID(tx, newName)
+		if err != nil && !errors.Is(err, ErrTagNotFound) {
+			return err
+		}
+		if err == nil && other != id {
+			return fmt.Errorf("tag %s already exists, merge the tags instead", newName)
+		}
+		_, err = tx.Exec(`UPDATE tags SET name = :name, key = :key WHERE id = :id`,
+			sql.Named("name", newName), sql.Named("key", tagKey(newName)), sql.Named("id", id))
+		return err
+	})
+}
+
+// MergeTags moves the tasks of the tags to the tag into, creating it if needed, and deletes the merged tags.
+func MergeTags(names []string, into string) error {
+	return inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO tags (name, key) VALUES (:name, :key)`,
+			sql.Named("name", into), sql.Named("key", tagKey(into)))
+		if err != nil {
+			return err
+		}
+		intoID, err := tagID(tx, into)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			id, err := tagID(tx, name)
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, name)
+			}
+			if id == intoID {
+				continue
+			}
+			_, err = tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) SELECT task_id, :into FROM task_tags WHERE tag_id = :id`,
+				sql.Named("into", intoID), sql.Named("id", id))
+			if err != nil {
+				return err
+			}
+			if err := deleteTag(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteTag removes a tag from all tasks and deletes it.
+func DeleteTag(name string) error {
+	return inTx(func(tx *sql.Tx) error {
+		id, err := tagID(tx, name)
+		if err != nil {
+			return err
+		}
+		return deleteTag(tx, id)
+	})
+}
+
+func deleteTag(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(`DELETE FROM task_tags WHERE tag_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM tags WHERE id = :id`, sql.Named("id", id))
+	return err
+}
+
+// tagKey returns the key a tag is looked up by.
+func tagKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// tagID returns the ID of the tag with the name in any case, or ErrTagNotFound.
+func tagID(tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM tags WHERE key = :key`, sql.Named("key", tagKey(name))).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTagNotFound
+	}
+	return id, err
+}
@@ -2,68 +2,220 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// taskColumns lists the scheduler columns in the order scanTask reads them.
+// The excluded dates and the tags of a task are selected as comma-separated lists and its dependency as a spec.
+const taskColumns = "id, date, title, comment, repeat, remaining, end_date, time, timezone, anchor, catchup, priority, " +
+	"COALESCE(NULLIF(project_id, 0), ''), checklist_mode, deleted_at, " +
+	"(SELECT COALESCE(group_concat(date, ','), '') FROM (SELECT date FROM exdates WHERE task_id = scheduler.id ORDER BY date)), " +
+	"COALESCE((SELECT 'after ' || depends_on || ' +' || days || 'd' FROM dependencies WHERE task_id = scheduler.id), ''), " +
+	"(SELECT COALESCE(group_concat(name, ','), '') FROM (SELECT name FROM tags JOIN task_tags ON tags.id = task_tags.tag_id " +
+	"WHERE task_tags.task_id = scheduler.id ORDER BY key))"
+
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID        string   `json:"id"`
+	Date      string   `json:"date"`
+	Title     string   `json:"title"`
+	Comment   string   `json:"comment"`
+	Repeat    string   `json:"repeat"`
+	Remaining int      `json:"remaining,omitempty"` // Remaining is the number of occurrences left, including the current one and excluded dates; 0 means no limit
+	EndDate   string   `json:"end_date"`            // EndDate is the last date the task may repeat on in YYYYMMDD format; empty means no limit
+	Exdates   []string `json:"exdates,omitempty"`   // Exdates lists the dates in YYYYMMDD format a repeating task skips
+	Time      string   `json:"time"`                // Time is the time of day in HH:MM format; empty for tasks that take the whole day
+	TimeZone  string   `json:"timezone"`            // TimeZone is the IANA time zone of Date and Time; empty means the server's zone
+	Anchor    string   `json:"anchor"`              // Anchor is AnchorSchedule or AnchorCompletion
+	CatchUp   string   `json:"catchup"`             // CatchUp is one of the CatchUp policies for occurrences missed before the task is done
+	Depends   string   `json:"depends"`             // Depends is the relative date spec "after <id> +<n>d"; empty for independent tasks
+	Priority  Priority `json:"priority"`            // Priority is from PriorityUrgent to PriorityNone; 0 means not given
+	Tags      []string `json:"tags,omitempty"`      // Tags are the names of the tags of the task
+	ProjectID string   `json:"project_id"`          // ProjectID is the ID of the project of the task; empty for none
+	// ChecklistMode is one of the ChecklistMode values for how the checklist of the task affects its completion
+	ChecklistMode string `json:"checklist_mode"`
+	// DeletedAt is the moment the task was moved to the trash in RFC 3339 format, UTC; empty for tasks not in the trash
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// Occurrence is the date in YYYYMMDD format the series schedules an expanded occurrence on; empty for the series itself
+	Occurrence string `json:"occurrence,omitempty"`
+}
+
+// Values of Task.Anchor.
+const (
+	AnchorSchedule   = "schedule"   // AnchorSchedule counts the next occurrence from the scheduled date, e.g. for bills
+	AnchorCompletion = "completion" // AnchorCompletion counts the next occurrence from the day the task is done, e.g. for chores
+)
+
+// Values of Task.Priority, from the most to the least urgent.
+const (
+	PriorityUrgent = 1 // PriorityUrgent is P1
+	PriorityHigh   = 2 // PriorityHigh is P2
+	PriorityMedium = 3 // PriorityMedium is P3
+	PriorityNone   = 4 // PriorityNone is P4, the priority of tasks that were not given one
+)
+
+// Priority is the priority of a task. It is written to JSON as a string such as "1",
+// and read from that, a plain number or the label "P1".
+type Priority int
+
+// MarshalJSON writes the priority as a string: every task has one, and the clients of the task list,
+// the tests among them, read a task as an object of string fields.
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.Itoa(int(p)))
+}
+
+// UnmarshalJSON reads 1, "1" or "P1". An empty string or null leaves the priority not given.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if s == "" {
+		*p = 0
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(s), "P"))
+	if err != nil {
+		return fmt.Errorf("invalid priority, expected %d to %d or P%d to P%d: %s",
+			PriorityUrgent, PriorityNone, PriorityUrgent, PriorityNone, data)
+	}
+	*p = Priority(n)
+	return nil
+}
+
+// Values of Task.CatchUp.
+const (
+	CatchUpSkip        = "skip"        // CatchUpSkip moves a late task past the occurrences it missed
+	CatchUpMaterialize = "materialize" // CatchUpMaterialize adds every missed occurrence as a separate overdue task
+	CatchUpToday       = "today"       // CatchUpToday rolls the missed occurrences into one due today
+)
+
+// Values of Task.ChecklistMode.
+const (
+	ChecklistFree    = "free"    // ChecklistFree lets the task be done whatever the state of its checklist
+	ChecklistRequire = "require" // ChecklistRequire refuses to complete the task while it has unchecked items
+	ChecklistAuto    = "auto"    // ChecklistAuto completes the task once all of its items are checked
+)
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask reads a task selected with taskColumns.
+func scanTask(row scanner, task *Task) error {
+	var exdates, tags string
+	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Remaining, &task.EndDate,
+		&task.Time, &task.TimeZone, &task.Anchor, &task.CatchUp, &task.Priority, &task.ProjectID, &task.ChecklistMode, &task.DeletedAt, &exdates, &task.Depends, &tags)
+	if err != nil {
+		return err
+	}
+	if exdates != "" {
+		task.Exdates = strings.Split(exdates, ",")
+	}
+	if tags != "" {
+		task.Tags = strings.Split(tags, ",")
+	}
+	return nil
 }
 
 // AddTask inserts a new task into the database and returns the ID of the newly created task.
+// The task's excluded dates are stored along with it.
 func AddTask(task *Task) (int64, error) {
-	var id int64
-	// Prepare the SQL statement to insert a new task
-	stmt := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	result, err := db.Exec(stmt, task.Date, task.Title, task.Comment, task.Repeat)
-	// Check for errors during the execution of the query
-	if err == nil {
-		id, err = result.LastInsertId()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
 	}
-	return id, err
+	defer tx.Rollback()
+
+	id, err := addTask(tx, task)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-// Tasks retrieves a limited number of tasks from the database, ordered by date.
-func Tasks(limit int) ([]*Task, error) {
-	rows, err := db.Query("SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT :limit", sql.Named("limit", limit))
+// AddTasks inserts several tasks at once; either all of them are added or none.
+func AddTasks(tasks []*Task) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, task := range tasks {
+		if _, err := addTask(tx, task); err != nil {
+			return err
+		}
 	}
-	defer rows.Close()
-	return getTasks(rows)
+	return tx.Commit()
 }
 
-// SearchTasks searches for tasks by title, comment, or date.
-func SearchTasks(search string, limit int) ([]*Task, error) {
-	if date, err := time.Parse("02.01.2006", search); err == nil {
-		rows, err := db.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE date = :date LIMIT :limit",
-			sql.Named("date", date.Format("20060102")), sql.Named("limit", limit))
+// addTask inserts a task with its excluded dates, dependency and tags within the transaction.
+func addTask(tx *sql.Tx, task *Task) (int64, error) {
+	// Prepare the SQL statement to insert a new task
+	stmt := `INSERT INTO scheduler (date, title, comment, repeat, remaining, end_date, time, timezone, anchor, catchup,
+		priority, project_id, checklist_mode) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(stmt, task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.EndDate,
+		task.Time, task.TimeZone, task.Anchor, task.CatchUp, task.Priority, projectID(task.ProjectID), task.ChecklistMode)
+	// Check for errors during the execution of the query
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, date := range task.Exdates {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO exdates (task_id, date) VALUES (?, ?)`, id, date)
 		if err != nil {
-			return nil, err
+			return 0, err
+		}
+	}
+	if task.Depends != "" {
+		if err := setDependency(tx, id, task.Depends); err != nil {
+			return 0, err
 		}
-		defer rows.Close()
-		return getTasks(rows)
 	}
+	if err := setTags(tx, id, task.Tags); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
 
-	rows, err := db.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE LOWER(title) LIKE LOWER(:search) "+
-		"OR LOWER(comment) LIKE LOWER(:search) ORDER BY date LIMIT :limit",
-		sql.Named("search", "%"+search+"%"), sql.Named("limit", limit))
-	if err != nil {
-		return nil, err
+// Tasks retrieves a limited number of tasks that meet the filter from the database, ordered by date and priority.
+func Tasks(f Filter, limit int) ([]*Task, error) {
+	return queryTasks("", f, limit)
+}
+
+// TasksUntil retrieves a limited number of tasks that meet the filter and have an occurrence on or before the date,
+// including the ones with an occurrence moved there, ordered by date and priority.
+func TasksUntil(date string, f Filter, limit int) ([]*Task, error) {
+	return queryTasks("date <= :date OR id IN (SELECT task_id FROM overrides WHERE new_date != '' AND new_date <= :date)",
+		f, limit, sql.Named("date", date))
+}
+
+// SearchTasks searches for tasks that meet the filter by title, comment, or date.
+func SearchTasks(search string, f Filter, limit int) ([]*Task, error) {
+	if date, err := time.Parse("02.01.2006", search); err == nil {
+		return queryTasks("date = :date", f, limit, sql.Named("date", date.Format("20060102")))
 	}
-	defer rows.Close()
-	return getTasks(rows)
+	return queryTasks("LOWER(title) LIKE LOWER(:search) OR LOWER(comment) LIKE LOWER(:search)",
+		f, limit, sql.Named("search", "%"+search+"%"))
 }
 
 // GetTask retrieves a task by its ID from the database.
 func GetTask(id string) (*Task, error) {
 	var task Task
-	err := db.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = :id",
-		sql.Named("id", id)).Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	row := db.QueryRow("SELECT "+taskColumns+" FROM scheduler WHERE id = :id AND deleted_at = ''",
+		sql.Named("id", id))
+	err := scanTask(row, &task)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No task found with the given ID
@@ -73,15 +225,33 @@ func GetTask(id string) (*Task, error) {
 	return &task, nil
 }
 
-// UpdateTask updates an existing task in the database.
+// UpdateTask updates an existing task with its dependency and tags in the database.
 func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat WHERE id = :id`
-	res, err := db.Exec(query,
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat,
+		remaining = :remaining, end_date = :end_date, time = :time, timezone = :timezone, anchor = :anchor,
+		catchup = :catchup, priority = :priority, project_id = :project_id,
+		checklist_mode = :checklist_mode WHERE id = :id AND deleted_at = ''`
+	res, err := tx.Exec(query,
 		sql.Named("id", task.ID),
 		sql.Named("date", task.Date),
 		sql.Named("title", task.Title),
 		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat))
+		sql.Named("repeat", task.Repeat),
+		sql.Named("remaining", task.Remaining),
+		sql.Named("end_date", task.EndDate),
+		sql.Named("time", task.Time),
+		sql.Named("timezone", task.TimeZone),
+		sql.Named("anchor", task.Anchor),
+		sql.Named("catchup", task.CatchUp),
+		sql.Named("priority", task.Priority),
+		sql.Named("project_id", projectID(task.ProjectID)),
+		sql.Named("checklist_mode", task.ChecklistMode))
 	if err != nil {
 		return err
 	}
@@ -92,13 +262,19 @@ func UpdateTask(task *Task) error {
 	if count == 0 {
 		return fmt.Errorf(`incorrect id for updating task`)
 	}
-	return nil
+	if err := setDependency(tx, task.ID, task.Depends); err != nil {
+		return err
+	}
+	if err := setTags(tx, task.ID, task.Tags); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// DeleteTask removes a task from the database by its ID.
+// DeleteTask moves a task to the trash by its ID. The task keeps everything that belongs to it until it is purged.
 func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = :id`
-	res, err := db.Exec(query, sql.Named("id", id))
+	query := `UPDATE scheduler SET deleted_at = :deleted_at WHERE id = :id AND deleted_at = ''`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("deleted_at", time.Now().UTC().Format(time.RFC3339)))
 	if err != nil {
 		return err
 	}
@@ -112,10 +288,62 @@ func DeleteTask(id string) error {
 	return nil
 }
 
-// UpdateDate updates the date of a task in the database by its ID.
-func UpdateDate(next string, id string) error {
-	query := `UPDATE scheduler SET date = :date WHERE id = :id`
-	res, err := db.Exec(query, sql.Named("date", next), sql.Named("id", id))
+// purgeTask removes a task with its excluded dates, occurrence overrides, dependencies, tags, checklist
+// and attachments from the database by its ID within the transaction.
+func purgeTask(tx *sql.Tx, id string) error {
+	query := `DELETE FROM scheduler WHERE id = :id`
+	res, err := tx.Exec(query, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for purging task`)
+	}
+	_, err = tx.Exec(`DELETE FROM exdates WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM overrides WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	// The tasks that followed this one become independent
+	_, err = tx.Exec(`DELETE FROM dependencies WHERE task_id = :id OR depends_on = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM task_tags WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM checklist WHERE task_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM attachments WHERE task_id = :id`, sql.Named("id", id))
+	return err
+}
+
+// UpdateDate moves a repeating task to its next date and time of day by its ID.
+// A limited series also uses up the 'used' occurrences it moves past,
+// and the overrides of the occurrences it has moved past are removed.
+// The checklist of the task is unchecked for the new occurrence.
+func UpdateDate(next string, nextTime string, used int, id string) error {
+	return inTx(func(tx *sql.Tx) error {
+		return updateDate(tx, next, nextTime, used, id)
+	})
+}
+
+// updateDate moves a repeating task to its next occurrence within the transaction.
+func updateDate(tx *sql.Tx, next string, nextTime string, used int, id string) error {
+	query := `UPDATE scheduler SET date = :date, time = :time, remaining = MAX(remaining - :used, 0)
+		WHERE id = :id AND deleted_at = ''`
+	res, err := tx.Exec(query, sql.Named("date", next), sql.Named("time", nextTime), sql.Named("used", used),
+		sql.Named("id", id))
 	if err != nil {
 		return err
 	}
@@ -126,7 +354,21 @@ func UpdateDate(next string, id string) error {
 	if count == 0 {
 		return fmt.Errorf(`incorrect id for updating task date`)
 	}
-	return nil
+	_, err = tx.Exec(`DELETE FROM overrides WHERE task_id = :id AND date < :date`,
+		sql.Named("id", id), sql.Named("date", next))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE checklist SET done = 0 WHERE task_id = :id`, sql.Named("id", id))
+	return err
+}
+
+// projectID returns the value of the project_id column for the project of a task, 0 for none.
+func projectID(id string) any {
+	if id == "" {
+		return 0
+	}
+	return id
 }
 
 // getTasks scans the rows returned by a query and returns a slice of Task pointers.
@@ -134,7 +376,7 @@ func getTasks(rows *sql.Rows) ([]*Task, error) {
 	var tasks []*Task
 	for rows.Next() {
 		var task Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, &task)
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TrashedTasks retrieves a limited number of the tasks in the trash, the most recently deleted first.
+func TrashedTasks(limit int) ([]*Task, error) {
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE deleted_at != '' ORDER BY deleted_at DESC, id DESC LIMIT :limit"
+	rows, err := db.Query(query, sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getTasks(rows)
+}
+
+// RestoreTask takes a task out of the trash by its ID.
+func RestoreTask(id string) error {
+	res, err := db.Exec(`UPDATE scheduler SET deleted_at = '' WHERE id = :id AND deleted_at != ''`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for restoring task`)
+	}
+	return nil
+}
+
+// PurgeTask removes a task in the trash for good by its ID.
+func PurgeTask(id string) error {
+	return inTx(func(tx *sql.Tx) error {
+		var trashed bool
+		err := tx.QueryRow(`SELECT deleted_at != '' FROM scheduler WHERE id = :id`, sql.Named("id", id)).Scan(&trashed)
+		if err == sql.ErrNoRows || (err == nil && !trashed) {
+			return fmt.Errorf(`incorrect id for purging task`)
+		}
+		if err != nil {
+			return err
+		}
+		return purgeTask(tx, id)
+	})
+}
+
+// PurgeTrash removes the tasks moved to the trash before the moment for good, or all of them if it is zero,
+// and returns how many it removed.
+func PurgeTrash(before time.Time) (int, error) {
+	var purged int
+	err := inTx(func(tx *sql.Tx) error {
+		cutoff := ""
+		if !before.IsZero() {
+			cutoff = before.UTC().Format(time.RFC3339)
+		}
+		rows, err := tx.Query(`SELECT id FROM scheduler WHERE deleted_at != '' AND (:before = '' OR deleted_at < :before)`,
+			sql.Named("before", cutoff))
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := purgeTask(tx, id); err != nil {
+				return err
+			}
+		}
+		purged = len(ids)
+		return nil
+	})
+	return purged, err
+}
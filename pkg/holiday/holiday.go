@@ -0,0 +1,225 @@
+package holiday
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const formatDate = "20060102" // Format for date in YYYYMMDD format
+
+// calendar holds the non-working and the extra working days.
+// Saturdays and Sundays are days off unless listed in workdays.
+type calendar struct {
+	holidays map[string]bool // holidays holds dates in YYYYMMDD format
+	yearly   map[string]bool // yearly holds MMDD of holidays that repeat every year
+	workdays map[string]bool // workdays holds weekend dates in YYYYMMDD format that are working days
+}
+
+var cal = calendar{}
+
+// Init loads the holiday calendar from a JSON or ICS file.
+// An empty file name leaves only Saturdays and Sundays as days off.
+//
+// The JSON file either lists the holidays, e.g. ["20240101", "20240108"],
+// or is an object with "holidays" and "workdays" lists, the latter for weekend days that are working days.
+// In an ICS file every VEVENT is a holiday on its DTSTART date; events with RRULE:FREQ=YEARLY repeat every year.
+func Init(file string) error {
+	cal = calendar{}
+	if file == "" {
+		return nil
+	}
+	var err error
+	if strings.EqualFold(filepath.Ext(file), ".ics") {
+		cal, err = loadICS(file)
+	} else {
+		cal, err = loadJSON(file)
+	}
+	if err != nil {
+		return fmt.Errorf("load holidays from %s: %w", file, err)
+	}
+	return nil
+}
+
+// loadJSON reads a calendar from a JSON file.
+func loadJSON(file string) (calendar, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return calendar{}, err
+	}
+
+	var list struct {
+		Holidays []string `json:"holidays"`
+		Workdays []string `json:"workdays"`
+	}
+	if err := json.Unmarshal(data, &list.Holidays); err != nil {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return calendar{}, err
+		}
+	}
+
+	c := calendar{holidays: map[string]bool{}, workdays: map[string]bool{}}
+	for _, d := range list.Holidays {
+		if _, err := time.Parse(formatDate, d); err != nil {
+			return calendar{}, fmt.Errorf("invalid holiday date: %s", d)
+		}
+		c.holidays[d] = true
+	}
+	for _, d := range list.Workdays {
+		if _, err := time.Parse(formatDate, d); err != nil {
+			return calendar{}, fmt.Errorf("invalid working date: %s", d)
+		}
+		c.workdays[d] = true
+	}
+	return c, nil
+}
+
+// loadICS reads a calendar from an iCalendar file.
+func loadICS(file string) (calendar, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return calendar{}, err
+	}
+	defer f.Close()
+
+	c := calendar{holidays: map[string]bool{}, yearly: map[string]bool{}}
+	var date string
+	var yearly bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value, _ := strings.Cut(line, ":")
+		// Parameters such as ;VALUE=DATE follow the property name
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		switch name {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				date, yearly = "", false
+			}
+		case "DTSTART":
+			date, _, _ = strings.Cut(value, "T")
+			if _, err := time.Parse(formatDate, date); err != nil {
+				return calendar{}, fmt.Errorf("invalid holiday date: %s", value)
+			}
+		case "RRULE":
+			yearly = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || date == "" {
+				continue
+			}
+			if yearly {
+				c.yearly[date[4:]] = true
+			} else {
+				c.holidays[date] = true
+			}
+		}
+	}
+	return c, scanner.Err()
+}
+
+// IsWorkday reports whether t is a working day.
+func IsWorkday(t time.Time) bool {
+	date := t.Format(formatDate)
+	if cal.workdays[date] {
+		return true
+	}
+	if cal.holidays[date] || cal.yearly[date[4:]] {
+		return false
+	}
+	return !isWeekend(t.Weekday())
+}
+
+// NextWorkday returns t if it is a working day, otherwise the first working day after it.
+func NextWorkday(t time.Time) time.Time {
+	for !IsWorkday(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// PrevWorkday returns t if it is a working day, otherwise the last working day before it.
+func PrevWorkday(t time.Time) time.Time {
+	for !IsWorkday(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// AddWorkdays returns the date n working days after t.
+func AddWorkdays(t time.Time, n int) time.Time {
+	for n > 0 {
+		t = t.AddDate(0, 0, 1)
+		if IsWorkday(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// CountWorkdays returns the number of working days after from up to and including to.
+// Weekends are counted arithmetically, so the cost depends on the size of the calendar
+// and the number of years between the dates rather than on the number of days.
+func CountWorkdays(from, to time.Time) int {
+	first, last := dayNumber(from)+1, dayNumber(to)
+	if first > last {
+		return 0
+	}
+	total := last - first + 1
+	count := total / 7 * 5
+	for n := last - total%7 + 1; n <= last; n++ {
+		if !isWeekend(weekday(n)) {
+			count++
+		}
+	}
+
+	// Holidays on weekdays, collected in a set as the same day may be listed in both lists
+	off := map[int]bool{}
+	for date := range cal.holidays {
+		if t, err := time.Parse(formatDate, date); err == nil {
+			off[dayNumber(t)] = true
+		}
+	}
+	for mmdd := range cal.yearly {
+		for year := from.Year(); year <= to.Year(); year++ {
+			if t, err := time.Parse(formatDate, fmt.Sprintf("%04d%s", year, mmdd)); err == nil {
+				off[dayNumber(t)] = true
+			}
+		}
+	}
+	for n := range off {
+		if n >= first && n <= last && !isWeekend(weekday(n)) && !cal.workdays[dateOf(n)] {
+			count--
+		}
+	}
+	// Weekend days that are working days
+	for date := range cal.workdays {
+		t, err := time.Parse(formatDate, date)
+		if n := dayNumber(t); err == nil && n >= first && n <= last && isWeekend(t.Weekday()) {
+			count++
+		}
+	}
+	return count
+}
+
+// dayNumber returns the number of days from 1970-01-01 to the date of t, ignoring its clock and time zone.
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// dateOf returns the date in YYYYMMDD format of a day number.
+func dateOf(n int) string {
+	return time.Unix(int64(n)*24*60*60, 0).UTC().Format(formatDate)
+}
+
+// weekday returns the day of the week of a day number; 1970-01-01 was a Thursday.
+func weekday(n int) time.Weekday {
+	return time.Weekday(((n+4)%7 + 7) % 7)
+}
+
+func isWeekend(wd time.Weekday) bool {
+	return wd == time.Saturday || wd == time.Sunday
+}
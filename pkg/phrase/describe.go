@@ -0,0 +1,530 @@
+package phrase
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+var (
+	enWeekdayNames = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	enMonthNames   = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+
+	// Russian weekday names for "по понедельникам" and "в понедельник", and the grammatical gender of each.
+	ruWeekdaysDative = []string{"", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+	ruWeekdaysAcc    = []string{"", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу", "воскресенье"}
+	ruWeekdayGender  = []int{0, genderMale, genderMale, genderFemale, genderMale, genderFemale, genderFemale, genderNeuter}
+	ruMonthsGenitive = []string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+
+	// Accusative ordinals by gender: "первый понедельник", "первую среду", "первое воскресенье".
+	ruOrdinalsAcc = map[int][3]string{
+		1: {"первый", "первую", "первое"}, 2: {"второй", "вторую", "второе"}, 3: {"третий", "третью", "третье"},
+		4: {"четвертый", "четвертую", "четвертое"}, 5: {"пятый", "пятую", "пятое"},
+		-1: {"последний", "последнюю", "последнее"}, -2: {"предпоследний", "предпоследнюю", "предпоследнее"},
+	}
+	enOrdinalNames = map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth", -1: "last", -2: "second to last"}
+
+	rruleDays = map[string]int{"MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6, "SU": 7}
+)
+
+const (
+	genderMale = iota
+	genderFemale
+	genderNeuter
+)
+
+// ruUnit holds the Russian forms of a unit: the accusative singular after "каждый"
+// and the forms used after 1, 2-4 and 5+, e.g. "день", "дня", "дней".
+type ruUnit struct {
+	gender         int
+	one, few, many string
+}
+
+var ruUnits = map[int]ruUnit{
+	unitMinute: {genderFemale, "минуту", "минуты", "минут"},
+	unitHour:   {genderMale, "час", "часа", "часов"},
+	unitDay:    {genderMale, "день", "дня", "дней"},
+	unitWeek:   {genderFemale, "неделю", "недели", "недель"},
+	unitMonth:  {genderMale, "месяц", "месяца", "месяцев"},
+	unitYear:   {genderMale, "год", "года", "лет"},
+	0:          {genderMale, "рабочий день", "рабочих дня", "рабочих дней"}, // business days
+}
+
+var enUnitNames = map[int]string{
+	unitMinute: "minute", unitHour: "hour", unitDay: "day", unitWeek: "week",
+	unitMonth: "month", unitYear: "year", 0: "working day",
+}
+
+// rule is a repeat string split into the parts a sentence is built from.
+type rule struct {
+	unit     int       // the unit of an interval rule, 0 for business days
+	interval int       // the interval of an interval rule
+	weekdays []int     // "w" and "wn": days of the week, 1 (Monday) to 7 (Sunday)
+	weeks    []int     // "wn": ISO week numbers
+	days     []int     // "m" and "q": days of the month or quarter, negative ones counted from the end
+	quarter  bool      // "q": days are counted within the quarter
+	nth      [][2]int  // "mw": pairs of the week number and the day of the week
+	months   []int     // "m" and "mw": the months the rule is restricted to
+	at       string    // "cron": the time of day, HH:MM
+	cron     string    // "cron": an expression that has no sentence form
+	raw      string    // an RRULE that has no sentence form
+	until    time.Time // the end conditions of the rule
+
+	count int
+	shift string
+}
+
+// Describe renders a repeat string as a sentence in the given language, "ru" (the default) or "en".
+func Describe(repeat, lang string) (string, error) {
+	parsed, err := recur.Parse(repeat)
+	if err != nil {
+		return "", err
+	}
+	r := fromRule(parsed)
+	switch lang {
+	case "", "ru":
+		return r.russian(), nil
+	case "en":
+		return r.english(), nil
+	}
+	return "", fmt.Errorf("unsupported language: %s", lang)
+}
+
+// fromRule takes the parts a sentence is built from out of a parsed rule.
+func fromRule(p recur.Rule) *rule {
+	r := &rule{unit: unitDay, interval: p.Interval, until: p.Until, count: p.Count}
+	switch p.Shift {
+	case recur.ShiftNext:
+		r.shift = "next"
+	case recur.ShiftPrev:
+		r.shift = "prev"
+	}
+	for _, m := range p.Months {
+		r.months = append(r.months, int(m))
+	}
+	slices.Sort(r.months)
+
+	switch p.Kind {
+	case recur.Workdays:
+		r.unit = 0
+	case recur.Hourly:
+		r.unit = unitHour
+	case recur.Minutely:
+		r.unit = unitMinute
+	case recur.Yearly:
+		r.unit = unitYear
+	case recur.Weekly:
+		r.unit = unitWeek
+		for _, wd := range p.Weekdays {
+			r.weekdays = append(r.weekdays, isoWeekday(wd))
+		}
+		slices.Sort(r.weekdays)
+	case recur.Monthly, recur.Quarterly:
+		// The canonical form already has the days in order
+		r.days, _ = splitInts(strings.Fields(p.String())[1])
+		r.quarter = p.Kind == recur.Quarterly
+	case recur.WeekNumber:
+		r.weeks = slices.Sorted(slices.Values(p.Weeks))
+		for _, wd := range p.Weekdays {
+			r.weekdays = append(r.weekdays, isoWeekday(wd))
+		}
+		slices.Sort(r.weekdays)
+	case recur.MonthWeekday:
+		for _, e := range p.Nth {
+			r.nth = append(r.nth, [2]int{e.N, isoWeekday(e.Weekday)})
+		}
+	case recur.Cron:
+		r.parseCron(strings.Fields(p.String())[1:6])
+	case recur.RRule:
+		r.parseRRule(strings.Fields(p.String())[0])
+	}
+	return r
+}
+
+// isoWeekday numbers the days of the week from 1 (Monday) to 7 (Sunday).
+func isoWeekday(wd time.Weekday) int {
+	if wd == time.Sunday {
+		return 7
+	}
+	return int(wd)
+}
+
+// parseRRule reads the parts of an RFC 5545 rule that map onto the short forms: FREQ with INTERVAL,
+// BYDAY with or without ordinals, BYMONTHDAY and BYMONTH. Any other rule is quoted as is.
+func (r *rule) parseRRule(repeat string) {
+	freqs := map[string]int{"DAILY": unitDay, "WEEKLY": unitWeek, "MONTHLY": unitMonth, "YEARLY": unitYear}
+	simple := true
+	for _, part := range strings.Split(repeat[len("RRULE:"):], ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			r.unit = freqs[value]
+		case "INTERVAL", "COUNT", "UNTIL", "WKST":
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, n := rruleDays[d[len(d)-2:]], 0
+				if d = d[:len(d)-2]; d != "" {
+					n, _ = strconv.Atoi(d)
+				}
+				if n == 0 {
+					r.weekdays = append(r.weekdays, wd)
+				} else {
+					r.nth = append(r.nth, [2]int{n, wd})
+				}
+			}
+		case "BYMONTHDAY":
+			days, _ := splitInts(value)
+			r.days = sortDays(days)
+		case "BYMONTH":
+			r.months, _ = splitInts(value)
+			slices.Sort(r.months)
+		default:
+			simple = false
+		}
+	}
+	// A rule on days of the month or on the n-th weekdays repeats every month or every year in the given months
+	monthly := r.interval == 1 && (r.unit == unitMonth || (r.unit == unitYear && len(r.months) > 0))
+	switch {
+	case len(r.nth) > 0:
+		simple = simple && monthly && len(r.weekdays) == 0 && len(r.days) == 0
+	case len(r.days) > 0:
+		simple = simple && monthly && len(r.weekdays) == 0
+	case len(r.weekdays) > 0:
+		simple = simple && r.unit == unitWeek && len(r.months) == 0
+	default:
+		simple = simple && len(r.months) == 0
+	}
+	if !simple {
+		r.raw = repeat
+	}
+}
+
+// parseCron reads a cron expression that runs at one time of day on every day, on days of the week
+// or on days of the month, in every month or in the given ones. Any other expression is quoted as is.
+func (r *rule) parseCron(fields []string) {
+	minute, errMinute := strconv.Atoi(fields[0])
+	hour, errHour := strconv.Atoi(fields[1])
+	days, okDays := cronList(fields[2], 1, 31)
+	months, okMonths := cronList(fields[3], 1, 12)
+	weekdays, okWeekdays := cronList(fields[4], 0, 7)
+	if errMinute != nil || errHour != nil || !okDays || !okMonths || !okWeekdays ||
+		len(days) > 0 && len(weekdays) > 0 || len(weekdays) > 0 && len(months) > 0 || len(months) > 0 && len(days) == 0 {
+		r.cron = strings.Join(fields, " ")
+		return
+	}
+	r.at = fmt.Sprintf("%02d:%02d", hour, minute)
+	r.interval, r.days, r.months = 1, days, months
+	if len(weekdays) > 0 {
+		r.unit = unitWeek
+		for _, wd := range weekdays {
+			if wd == 0 {
+				wd = 7 // cron counts Sunday as both 0 and 7
+			}
+			r.weekdays = appendUnique(r.weekdays, wd)
+		}
+		slices.Sort(r.weekdays)
+	}
+}
+
+// cronList reads a cron field of plain numbers and ranges, e.g. "1-5,7", returning nil for "*" and "?".
+// It reports false for steps, names and the other extensions.
+func cronList(field string, lo, hi int) ([]int, bool) {
+	if field == "*" || field == "?" {
+		return nil, true
+	}
+	var res []int
+	for _, v := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(v, "-")
+		a, errFrom := strconv.Atoi(from)
+		b, errTo := a, error(nil)
+		if isRange {
+			b, errTo = strconv.Atoi(to)
+		}
+		if errFrom != nil || errTo != nil || a < lo || b > hi || a > b {
+			return nil, false
+		}
+		for n := a; n <= b; n++ {
+			res = appendUnique(res, n)
+		}
+	}
+	slices.Sort(res)
+	return res, true
+}
+
+// english renders the rule in English.
+func (r *rule) english() string {
+	var s string
+	switch {
+	case r.raw != "":
+		s = "by the rule " + r.raw
+	case r.cron != "":
+		s = "on the cron schedule " + r.cron
+	case len(r.days) > 0:
+		days := make([]string, len(r.days))
+		for i, d := range r.days {
+			days[i] = enDay(d)
+		}
+		if r.quarter {
+			s = "on the " + joinWords(days, "and") + " day of every quarter"
+		} else {
+			s = "on the " + joinWords(days, "and") + " day of " + enMonthList(r.months)
+		}
+	case len(r.nth) > 0:
+		days := make([]string, len(r.nth))
+		for i, p := range r.nth {
+			days[i] = enOrdinal(p[0]) + " " + enWeekdayNames[p[1]]
+		}
+		s = "on the " + joinWords(days, "and") + " of " + enMonthList(r.months)
+	case len(r.weeks) > 0:
+		names := make([]string, len(r.weekdays))
+		for i, wd := range r.weekdays {
+			names[i] = enWeekdayNames[wd]
+		}
+		weeks := make([]string, len(r.weeks))
+		for i, w := range r.weeks {
+			weeks[i] = strconv.Itoa(w)
+		}
+		s = "on " + joinWords(names, "and") + " of ISO week"
+		if len(weeks) > 1 {
+			s += "s"
+		}
+		s += " " + joinWords(weeks, "and")
+	default:
+		s = enEvery(r.interval, r.unit)
+		if len(r.weekdays) > 0 {
+			names := make([]string, len(r.weekdays))
+			for i, wd := range r.weekdays {
+				names[i] = enWeekdayNames[wd]
+			}
+			s += " on " + joinWords(names, "and")
+		}
+	}
+
+	if r.at != "" {
+		s += " at " + r.at
+	}
+	// A rule quoted as is already has its end conditions in it
+	if !r.until.IsZero() && r.raw == "" {
+		s += ", until " + r.until.Format("January 2, 2006")
+	}
+	if r.raw == "" && r.count == 1 {
+		s += ", once"
+	} else if r.raw == "" && r.count > 1 {
+		s += fmt.Sprintf(", %d times", r.count)
+	}
+	switch r.shift {
+	case "next":
+		s += ", moved to the next working day on holidays"
+	case "prev":
+		s += ", moved to the previous working day on holidays"
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// russian renders the rule in Russian.
+func (r *rule) russian() string {
+	var s string
+	switch {
+	case r.raw != "":
+		s = "по правилу " + r.raw
+	case r.cron != "":
+		s = "по расписанию cron " + r.cron
+	case len(r.days) > 0:
+		days := make([]string, len(r.days))
+		for i, d := range r.days {
+			days[i] = ruDay(d)
+		}
+		if r.quarter {
+			s = joinWords(days, "и") + " дня каждого квартала"
+		} else {
+			s = joinWords(days, "и") + " числа " + ruMonthList(r.months)
+		}
+	case len(r.nth) > 0:
+		days := make([]string, len(r.nth))
+		for i, p := range r.nth {
+			gender := ruWeekdayGender[p[1]]
+			ordinal := ruOrdinal(p[0], gender)
+			prep := "в "
+			if strings.HasPrefix(ordinal, "вт") {
+				prep = "во "
+			}
+			days[i] = prep + ordinal + " " + ruWeekdaysAcc[p[1]]
+		}
+		s = joinWords(days, "и") + " " + ruMonthList(r.months)
+	case len(r.weeks) > 0:
+		names := make([]string, len(r.weekdays))
+		for i, wd := range r.weekdays {
+			names[i] = ruWeekdaysDative[wd]
+		}
+		weeks := make([]string, len(r.weeks))
+		for i, w := range r.weeks {
+			weeks[i] = strconv.Itoa(w)
+		}
+		s = "по " + joinWords(names, "и") + " ISO-недел" + ruPlural(len(weeks), "и", "ь", "ь") + " " + joinWords(weeks, "и")
+	default:
+		s = ruEvery(r.interval, r.unit)
+		if len(r.weekdays) > 0 {
+			names := make([]string, len(r.weekdays))
+			for i, wd := range r.weekdays {
+				names[i] = ruWeekdaysDative[wd]
+			}
+			s += " по " + joinWords(names, "и")
+		}
+	}
+
+	if r.at != "" {
+		s += " в " + r.at
+	}
+	// A rule quoted as is already has its end conditions in it
+	if !r.until.IsZero() && r.raw == "" {
+		s += fmt.Sprintf(", до %d %s %d", r.until.Day(), ruMonthsGenitive[r.until.Month()], r.until.Year())
+	}
+	if r.count > 0 && r.raw == "" {
+		s += fmt.Sprintf(", %d %s", r.count, ruPlural(r.count, "раз", "раза", "раз"))
+	}
+	switch r.shift {
+	case "next":
+		s += ", с переносом с выходных на следующий рабочий день"
+	case "prev":
+		s += ", с переносом с выходных на предыдущий рабочий день"
+	}
+	// Capitalize the first letter, which may take several bytes
+	first := []rune(s)[0]
+	return strings.ToUpper(string(first)) + s[len(string(first)):]
+}
+
+// enEvery returns "every day", "every 3 weeks" and the like.
+func enEvery(n, unit int) string {
+	if n == 1 {
+		return "every " + enUnitNames[unit]
+	}
+	return fmt.Sprintf("every %d %ss", n, enUnitNames[unit])
+}
+
+// ruEvery returns "каждый день", "каждые 3 недели", "каждый 21 день" and the like.
+func ruEvery(n, unit int) string {
+	u := ruUnits[unit]
+	every := [3]string{"каждый", "каждую", "каждое"}[u.gender]
+	if n == 1 {
+		return every + " " + u.one
+	}
+	if n%10 == 1 && n%100 != 11 {
+		return fmt.Sprintf("%s %d %s", every, n, u.one)
+	}
+	return fmt.Sprintf("каждые %d %s", n, ruPlural(n, u.one, u.few, u.many))
+}
+
+// ruPlural picks the Russian noun form for the number n.
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	}
+	return many
+}
+
+// enDay returns "1st", "22nd", "last" or "3rd to last" for a day of the month.
+func enDay(d int) string {
+	switch {
+	case d == -1:
+		return "last"
+	case d < 0:
+		return enSuffix(-d) + " to last"
+	}
+	return enSuffix(d)
+}
+
+func enSuffix(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func enOrdinal(n int) string {
+	if name, ok := enOrdinalNames[n]; ok {
+		return name
+	}
+	return enSuffix(-n) + " to last"
+}
+
+// ruDay returns "1-го", "последнего" or "3-го с конца" for a day of the month.
+func ruDay(d int) string {
+	switch {
+	case d == -1:
+		return "последнего"
+	case d == -2:
+		return "предпоследнего"
+	case d < 0:
+		return fmt.Sprintf("%d-го с конца", -d)
+	}
+	return fmt.Sprintf("%d-го", d)
+}
+
+func ruOrdinal(n, gender int) string {
+	if forms, ok := ruOrdinalsAcc[n]; ok {
+		return forms[gender]
+	}
+	return fmt.Sprintf("%d-%s с конца", -n, [3]string{"й", "ю", "е"}[gender])
+}
+
+// enMonthList returns "every month" or "February and August".
+func enMonthList(months []int) string {
+	if len(months) == 0 {
+		return "every month"
+	}
+	names := make([]string, len(months))
+	for i, m := range months {
+		names[i] = enMonthNames[m]
+	}
+	return joinWords(names, "and")
+}
+
+// ruMonthList returns "каждого месяца" or "февраля и августа".
+func ruMonthList(months []int) string {
+	if len(months) == 0 {
+		return "каждого месяца"
+	}
+	names := make([]string, len(months))
+	for i, m := range months {
+		names[i] = ruMonthsGenitive[m]
+	}
+	return joinWords(names, "и")
+}
+
+// joinWords joins words as "a, b and c".
+func joinWords(words []string, and string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " " + and + " " + words[len(words)-1]
+}
+
+// splitInts parses a comma-separated list of numbers.
+func splitInts(s string) ([]int, error) {
+	var res []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
@@ -0,0 +1,202 @@
+package phrase
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parse turns an English or Russian phrase such as "every 2 weeks on Monday and Friday"
+// or "каждый последний день месяца" into the canonical repeat string, e.g. "w 1,5 /2" or "m -1".
+// A word it does not know is an error rather than being skipped, as it may change the meaning of the phrase.
+func Parse(text string) (string, error) {
+	var tokens []token
+	for _, word := range splitWords(text) {
+		t := classify(word)
+		if t.kind == kindOther {
+			return "", fmt.Errorf("unknown word %q in %q", word, text)
+		}
+		tokens = append(tokens, t)
+	}
+
+	var (
+		weekdays, monthDays, months, ordinals []int
+		interval, unit                        int
+		business, monthContext                bool
+		ordinalDay                            bool // an ordinal is followed by the word "day": "last day"
+	)
+	for i, t := range tokens {
+		next := token{}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		switch t.kind {
+		case kindNumber:
+			// A number before a unit is the interval, any other number is a day of the month
+			if next.kind == kindUnit || next.kind == kindBusiness {
+				interval = t.value
+			} else if next.kind != kindNumber {
+				monthDays = appendUnique(monthDays, t.value)
+			}
+		case kindDay:
+			if next.kind == kindUnit && next.value == unitDay {
+				ordinals = appendUnique(ordinals, t.value) // "every 3rd day", "15th day of the month"
+				continue
+			}
+			monthDays = appendUnique(monthDays, t.value)
+		case kindOrdinal:
+			if next.kind == kindUnit && (next.value == unitWeek || next.value == unitYear) {
+				interval = t.value // "every second week"
+				continue
+			}
+			ordinals = appendUnique(ordinals, t.value)
+		case kindWeekday:
+			weekdays = appendUnique(weekdays, t.value)
+		case kindWeekdays:
+			weekdays = appendUnique(weekdays, 1, 2, 3, 4, 5)
+		case kindWeekend:
+			weekdays = appendUnique(weekdays, 6, 7)
+		case kindMonth:
+			months = appendUnique(months, t.value)
+		case kindBusiness:
+			business = true
+		case kindMonthDay:
+			monthContext = true
+		case kindUnit:
+			if t.value == unitDay && i > 0 && (tokens[i-1].kind == kindOrdinal || tokens[i-1].kind == kindDay) {
+				ordinalDay = true
+			}
+			if t.value == unitFortnight {
+				interval, t.value = 2*max(interval, 1), unitWeek
+			}
+			if t.value == unitMonth && unit != 0 {
+				monthContext = true // "every year ... of the month"
+				continue
+			}
+			if t.value == unitMonth {
+				monthContext = true
+			}
+			if unit == 0 || t.value != unitDay || !ordinalDay {
+				unit = t.value
+			}
+		}
+	}
+	if len(months) > 0 {
+		monthContext = true
+	}
+	if interval < 0 {
+		return "", fmt.Errorf("invalid interval in %q", text)
+	}
+
+	switch {
+	case unit == unitMinute || unit == unitHour:
+		name := map[int]string{unitMinute: "min", unitHour: "h"}[unit]
+		return fmt.Sprintf("%s %d", name, max(interval, 1)), nil
+
+	case business:
+		return fmt.Sprintf("bd %d", max(interval, 1)), nil
+
+	case len(weekdays) > 0 && len(ordinals) > 0:
+		var pairs []string
+		for _, o := range ordinals {
+			for _, wd := range weekdays {
+				pairs = append(pairs, fmt.Sprintf("%d:%d", o, wd))
+			}
+		}
+		return withMonths("mw "+strings.Join(pairs, ","), months), nil
+
+	case len(weekdays) > 0:
+		if len(months) > 0 {
+			return "", fmt.Errorf("months cannot be combined with days of the week in %q", text)
+		}
+		slices.Sort(weekdays)
+		repeat := "w " + joinInts(weekdays)
+		if unit == unitWeek && interval > 1 {
+			repeat += " /" + strconv.Itoa(interval)
+		}
+		return repeat, nil
+
+	case ordinalDay && monthContext, len(monthDays) > 0:
+		if unit == unitMonth && interval > 1 {
+			return "", fmt.Errorf("monthly rules cannot skip months in %q", text)
+		}
+		days := slices.Clone(monthDays)
+		if ordinalDay {
+			days = append(days, ordinals...)
+		}
+		return withMonths("m "+joinInts(sortDays(days)), months), nil
+
+	case ordinalDay && len(ordinals) == 1 && ordinals[0] > 0:
+		return fmt.Sprintf("d %d", ordinals[0]), nil // "every second day"
+
+	case unit == unitDay:
+		return fmt.Sprintf("d %d", max(interval, 1)), nil
+
+	case unit == unitWeek:
+		return fmt.Sprintf("d %d", 7*max(interval, 1)), nil
+
+	case unit == unitYear:
+		if interval > 1 {
+			return fmt.Sprintf("y %d", interval), nil
+		}
+		return "y", nil
+
+	case unit == unitMonth:
+		return "", fmt.Errorf("specify the day of the month in %q", text)
+	}
+	return "", fmt.Errorf("cannot understand the repeat phrase %q", text)
+}
+
+// splitWords lower-cases the text and splits it into words, keeping "15-го" as one word.
+func splitWords(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	var res []string
+	for _, w := range words {
+		if w = strings.ReplaceAll(strings.Trim(w, "-"), "-", ""); w != "" {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// withMonths appends the month list to a monthly rule.
+func withMonths(repeat string, months []int) string {
+	if len(months) == 0 {
+		return repeat
+	}
+	slices.Sort(months)
+	return repeat + " " + joinInts(months)
+}
+
+// sortDays orders the days of the month with the days counted from the end last.
+func sortDays(days []int) []int {
+	slices.SortFunc(days, func(a, b int) int {
+		if (a < 0) != (b < 0) {
+			return b - a
+		}
+		return a - b
+	})
+	return slices.Compact(days)
+}
+
+func appendUnique(list []int, values ...int) []int {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
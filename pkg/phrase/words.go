@@ -0,0 +1,179 @@
+package phrase
+
+import (
+	"slices"
+	"strings"
+)
+
+// kind classifies a word of a phrase.
+type kind int
+
+const (
+	kindOther    kind = iota // a word the parser does not know
+	kindFiller               // "every", "каждый": joins the other words without a meaning of its own
+	kindNumber               // a plain number: "3"
+	kindDay                  // a day of the month: "15th", "15-го"
+	kindOrdinal              // "second", "последний"
+	kindWeekday              // "monday", "понедельникам"
+	kindMonth                // "february", "августе"
+	kindUnit                 // "days", "недели"
+	kindWeekdays             // "weekdays", "будням": Monday to Friday
+	kindWeekend              // "weekend", "выходным": Saturday and Sunday
+	kindBusiness             // "working", "рабочий": working days of the holiday calendar
+	kindMonthDay             // "число": marks the numbers around it as days of the month
+)
+
+// Units of kindUnit words.
+const (
+	unitMinute = iota + 1
+	unitHour
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+	unitFortnight // two weeks
+)
+
+// token is a classified word. value holds the number, ordinal, weekday (1-7), month (1-12) or unit.
+type token struct {
+	kind  kind
+	value int
+}
+
+var (
+	enWeekdays = map[string]int{
+		"monday": 1, "mondays": 1, "mon": 1,
+		"tuesday": 2, "tuesdays": 2, "tue": 2, "tues": 2,
+		"wednesday": 3, "wednesdays": 3, "wed": 3,
+		"thursday": 4, "thursdays": 4, "thu": 4, "thur": 4, "thurs": 4,
+		"friday": 5, "fridays": 5, "fri": 5,
+		"saturday": 6, "saturdays": 6, "sat": 6,
+		"sunday": 7, "sundays": 7, "sun": 7,
+	}
+	enMonths = map[string]int{
+		"january": 1, "jan": 1, "february": 2, "feb": 2, "march": 3, "mar": 3,
+		"april": 4, "apr": 4, "may": 5, "june": 6, "jun": 6, "july": 7, "jul": 7,
+		"august": 8, "aug": 8, "september": 9, "sep": 9, "sept": 9,
+		"october": 10, "oct": 10, "november": 11, "nov": 11, "december": 12, "dec": 12,
+	}
+	enOrdinals = map[string]int{
+		"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5,
+		"last": -1, "penultimate": -2,
+	}
+	enUnits = map[string]token{
+		"minute": {kindUnit, unitMinute}, "minutes": {kindUnit, unitMinute}, "min": {kindUnit, unitMinute}, "mins": {kindUnit, unitMinute},
+		"hour": {kindUnit, unitHour}, "hours": {kindUnit, unitHour}, "hourly": {kindUnit, unitHour},
+		"day": {kindUnit, unitDay}, "days": {kindUnit, unitDay}, "daily": {kindUnit, unitDay},
+		"week": {kindUnit, unitWeek}, "weeks": {kindUnit, unitWeek}, "weekly": {kindUnit, unitWeek},
+		"month": {kindUnit, unitMonth}, "months": {kindUnit, unitMonth}, "monthly": {kindUnit, unitMonth},
+		"year": {kindUnit, unitYear}, "years": {kindUnit, unitYear}, "yearly": {kindUnit, unitYear}, "annually": {kindUnit, unitYear},
+		"weekday": {kindWeekdays, 0}, "weekdays": {kindWeekdays, 0},
+		"weekend": {kindWeekend, 0}, "weekends": {kindWeekend, 0},
+		"working": {kindBusiness, 0}, "business": {kindBusiness, 0}, "workday": {kindBusiness, 0}, "workdays": {kindBusiness, 0},
+		"fortnight": {kindUnit, unitFortnight}, "fortnights": {kindUnit, unitFortnight}, "fortnightly": {kindUnit, unitFortnight},
+		"other": {kindNumber, 2},
+	}
+	enFillers = []string{"every", "each", "on", "and", "of", "the", "a", "an", "in", "per"}
+
+	// The Russian words change their endings, so they are matched by stem.
+	ruWeekdays = []struct {
+		stem  string
+		value int
+	}{
+		{"понедельн", 1}, {"вторник", 2}, {"сред", 3}, {"четверг", 4}, {"пятниц", 5}, {"суббот", 6}, {"воскресен", 7},
+	}
+	ruMonths = []struct {
+		stem  string
+		value int
+	}{
+		{"январ", 1}, {"феврал", 2}, {"март", 3}, {"апрел", 4}, {"мая", 5}, {"мае", 5}, {"май", 5}, {"июн", 6},
+		{"июл", 7}, {"август", 8}, {"сентябр", 9}, {"октябр", 10}, {"ноябр", 11}, {"декабр", 12},
+	}
+	ruOrdinals = []struct {
+		stem  string
+		value int
+	}{
+		{"предпоследн", -2}, {"последн", -1}, {"перв", 1}, {"втор", 2}, {"трет", 3}, {"четверт", 4},
+		{"пятый", 5}, {"пятую", 5}, {"пятое", 5}, {"пятого", 5}, {"пятой", 5},
+	}
+	ruWords = []struct {
+		stem string
+		tok  token
+	}{
+		{"ежеминутн", token{kindUnit, unitMinute}}, {"минут", token{kindUnit, unitMinute}},
+		{"ежечасн", token{kindUnit, unitHour}}, {"час", token{kindUnit, unitHour}},
+		{"ежедневн", token{kindUnit, unitDay}}, {"день", token{kindUnit, unitDay}}, {"дня", token{kindUnit, unitDay}},
+		{"дней", token{kindUnit, unitDay}}, {"сутк", token{kindUnit, unitDay}}, {"суток", token{kindUnit, unitDay}},
+		{"еженедельн", token{kindUnit, unitWeek}}, {"недел", token{kindUnit, unitWeek}},
+		{"ежемесячн", token{kindUnit, unitMonth}}, {"месяц", token{kindUnit, unitMonth}},
+		{"ежегодн", token{kindUnit, unitYear}}, {"год", token{kindUnit, unitYear}}, {"лет", token{kindUnit, unitYear}},
+		{"будн", token{kindWeekdays, 0}}, {"выходн", token{kindWeekend, 0}}, {"рабоч", token{kindBusiness, 0}},
+		{"числ", token{kindMonthDay, 0}}, {"через", token{kindNumber, 2}},
+		{"кажд", token{kindFiller, 0}},
+	}
+	ruFillers = []string{"в", "во", "по", "и"}
+)
+
+// classify returns the token for a lower-case word.
+func classify(word string) token {
+	if n, suffix, ok := splitNumber(word); ok {
+		switch suffix {
+		case "":
+			return token{kindNumber, n}
+		case "st", "nd", "rd", "th", "го", "е", "ое", "ого", "й", "ый", "ий":
+			return token{kindDay, n}
+		}
+		return token{kindOther, 0}
+	}
+
+	if slices.Contains(enFillers, word) || slices.Contains(ruFillers, word) {
+		return token{kindFiller, 0}
+	}
+	if v, ok := enWeekdays[word]; ok {
+		return token{kindWeekday, v}
+	}
+	if v, ok := enMonths[word]; ok {
+		return token{kindMonth, v}
+	}
+	if v, ok := enOrdinals[word]; ok {
+		return token{kindOrdinal, v}
+	}
+	if t, ok := enUnits[word]; ok {
+		return t
+	}
+
+	for _, w := range ruWeekdays {
+		if strings.HasPrefix(word, w.stem) {
+			return token{kindWeekday, w.value}
+		}
+	}
+	for _, m := range ruMonths {
+		if strings.HasPrefix(word, m.stem) {
+			return token{kindMonth, m.value}
+		}
+	}
+	for _, o := range ruOrdinals {
+		if strings.HasPrefix(word, o.stem) {
+			return token{kindOrdinal, o.value}
+		}
+	}
+	for _, w := range ruWords {
+		if strings.HasPrefix(word, w.stem) {
+			return w.tok
+		}
+	}
+	return token{kindOther, 0}
+}
+
+// splitNumber splits a word like "15th" into its leading number and the rest.
+func splitNumber(word string) (int, string, bool) {
+	n, i := 0, 0
+	for i < len(word) && word[i] >= '0' && word[i] <= '9' {
+		n = n*10 + int(word[i]-'0')
+		i++
+		if n > 100000 {
+			return 0, "", false
+		}
+	}
+	return n, word[i:], i > 0
+}
@@ -0,0 +1,304 @@
+package recur
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMonths and cronWeekdays hold the names accepted in the month and day-of-week fields.
+var (
+	cronMonths = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	cronWeekdays = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+)
+
+// cronNthWeekday is a day-of-week entry such as "1#2" (second Monday) or "5L" (last Friday, n == -1).
+type cronNthWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+// cronSchedule holds a parsed five-field cron expression: minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	expr string // expr is the expression as given, for Rule.String
+
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	lastDay        bool             // "L" in the day-of-month field
+	lastWorkday    bool             // "LW" in the day-of-month field
+	nearestWorkday []int            // "15W" in the day-of-month field
+	nthWeekdays    []cronNthWeekday // "1#2" and "5L" in the day-of-week field
+
+	anyDay     bool // the day-of-month field is "*" or "?"
+	anyWeekday bool // the day-of-week field is "*" or "?"
+}
+
+// parseCron parses the expression part of a "cron <expr>" repeat pattern.
+func parseCron(expr []string) (*cronSchedule, error) {
+	if len(expr) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields: %s", strings.Join(expr, " "))
+	}
+	c := &cronSchedule{expr: strings.Join(expr, " ")}
+	var err error
+	if c.minutes, err = parseCronField(expr[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron minute: %w", err)
+	}
+	if c.hours, err = parseCronField(expr[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron hour: %w", err)
+	}
+	if err = c.parseDays(expr[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month: %w", err)
+	}
+	if c.months, err = parseCronField(expr[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("invalid cron month: %w", err)
+	}
+	if err = c.parseWeekdays(expr[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week: %w", err)
+	}
+	return c, nil
+}
+
+// parseDays parses the day-of-month field including the L, LW and nW extensions.
+func (c *cronSchedule) parseDays(field string) error {
+	if field == "*" || field == "?" {
+		c.anyDay = true
+	}
+	var plain []string
+	for _, v := range strings.Split(field, ",") {
+		switch {
+		case v == "L":
+			c.lastDay = true
+		case v == "LW":
+			c.lastWorkday = true
+		case strings.HasSuffix(v, "W"):
+			day, err := strconv.Atoi(strings.TrimSuffix(v, "W"))
+			if err != nil || day < 1 || day > 31 {
+				return fmt.Errorf("%s", v)
+			}
+			c.nearestWorkday = append(c.nearestWorkday, day)
+		default:
+			plain = append(plain, v)
+		}
+	}
+	var err error
+	c.days = map[int]bool{}
+	if len(plain) > 0 {
+		c.days, err = parseCronField(strings.Join(plain, ","), 1, 31, nil)
+	}
+	return err
+}
+
+// parseWeekdays parses the day-of-week field including the n#k and nL extensions.
+// Both 0 and 7 mean Sunday.
+func (c *cronSchedule) parseWeekdays(field string) error {
+	if field == "*" || field == "?" {
+		c.anyWeekday = true
+	}
+	var plain []string
+	for _, v := range strings.Split(field, ",") {
+		day, nth, isNth := strings.Cut(v, "#")
+		isLast := !isNth && len(v) > 1 && strings.HasSuffix(v, "L")
+		if isLast {
+			day = strings.TrimSuffix(v, "L")
+		}
+		if !isNth && !isLast {
+			plain = append(plain, v)
+			continue
+		}
+		wd, err := cronValue(day, cronWeekdays)
+		if err != nil || wd < 0 || wd > 7 {
+			return fmt.Errorf("%s", v)
+		}
+		n := -1
+		if isNth {
+			n, err = strconv.Atoi(nth)
+			if err != nil || n < 1 || n > 5 {
+				return fmt.Errorf("%s", v)
+			}
+		}
+		c.nthWeekdays = append(c.nthWeekdays, cronNthWeekday{n: n, weekday: time.Weekday(wd % 7)})
+	}
+	c.weekdays = map[int]bool{}
+	if len(plain) == 0 {
+		return nil
+	}
+	days, err := parseCronField(strings.Join(plain, ","), 0, 7, cronWeekdays)
+	if err != nil {
+		return err
+	}
+	for d := range days {
+		c.weekdays[d%7] = true
+	}
+	return nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// such as "*", "*/15", "1-5", "10-30/5" or "MON,WED" into a set of values within [lo, hi].
+func parseCronField(field string, lo, hi int, names map[string]int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, v := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(v, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("%s", v)
+			}
+		}
+
+		start, end := lo, hi
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			start, err1 = cronValue(from, names)
+			end, err2 = cronValue(to, names)
+			if err1 != nil || err2 != nil || start > end {
+				return nil, fmt.Errorf("%s", v)
+			}
+		default:
+			var err error
+			start, err = cronValue(rng, names)
+			if err != nil {
+				return nil, fmt.Errorf("%s", v)
+			}
+			// "5/10" means every 10 starting at 5, a single value otherwise
+			if !hasStep {
+				end = start
+			}
+		}
+		if start < lo || end > hi {
+			return nil, fmt.Errorf("%s", v)
+		}
+		for i := start; i <= end; i += step {
+			set[i] = true
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a number or a name from names.
+func cronValue(v string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(v)]; ok {
+		return n, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// matchDay reports whether the schedule fires on the given day.
+// As in cron, when both the day-of-month and day-of-week fields are restricted, either may match.
+func (c *cronSchedule) matchDay(d time.Time) bool {
+	if !c.months[int(d.Month())] {
+		return false
+	}
+	dom := c.matchMonthDay(d)
+	dow := c.matchWeekday(d)
+	if c.anyDay || c.anyWeekday {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) matchMonthDay(d time.Time) bool {
+	if c.anyDay || c.days[d.Day()] {
+		return true
+	}
+	lastDay := lastDayOfMonth(d)
+	if c.lastDay && d.Day() == lastDay {
+		return true
+	}
+	if c.lastWorkday && d.Day() == nearestWorkday(d, lastDay, lastDay) {
+		return true
+	}
+	for _, day := range c.nearestWorkday {
+		if day <= lastDay && d.Day() == nearestWorkday(d, day, lastDay) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cronSchedule) matchWeekday(d time.Time) bool {
+	if c.anyWeekday || c.weekdays[int(d.Weekday())] {
+		return true
+	}
+	for _, nth := range c.nthWeekdays {
+		if nth.weekday != d.Weekday() {
+			continue
+		}
+		if nth.n > 0 && (d.Day()-1)/7+1 == nth.n {
+			return true
+		}
+		if nth.n < 0 && d.Day()+7 > lastDayOfMonth(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestWorkday returns the Monday-to-Friday day closest to day within the month of t,
+// never crossing into a neighbouring month.
+func nearestWorkday(t time.Time, day, lastDay int) int {
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == lastDay {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+// nextTime returns the first minute after the given moment on which the schedule fires.
+func (c *cronSchedule) nextTime(after time.Time) (time.Time, error) {
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	for i := 0; i < 366*30; i++ {
+		if c.matchDay(day) {
+			for h := 0; h < 24; h++ {
+				if !c.hours[h] {
+					continue
+				}
+				for m := 0; m < 60; m++ {
+					t := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location())
+					if c.minutes[m] && t.After(after) {
+						return t, nil
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return after, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+// addCron finds the next day after now on which the cron expression fires.
+// The minute and hour fields only matter for tasks with a time of day, see Rule.NextTime.
+func addCron(date, now time.Time, c *cronSchedule) (time.Time, error) {
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+	// Leap days combined with a day of week may take several years to come round
+	for i := 0; i < 366*30; i++ {
+		if c.matchDay(date) {
+			return date, nil
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
@@ -0,0 +1,74 @@
+package recur
+
+import (
+	"fmt"
+	"time"
+)
+
+// Iterator walks the occurrences of a series one after another, starting from Start, which is the first of them.
+// It counts the occurrences as it goes, so walking n of them takes n steps, whereas calling Next for each one
+// walks a limited series from Start every time.
+type Iterator struct {
+	r     Rule
+	timed bool      // timed iterators move the time of day like NextTime, the others step by dates like Next
+	cur   time.Time // cur is the current occurrence before its shift
+	last  time.Time // last is the current occurrence as returned
+	n     int       // n is the number of the current occurrence, Start being the first
+}
+
+// Iterate returns an iterator over the dates of the series, which follow one another as with Next.
+func (r Rule) Iterate() *Iterator {
+	return &Iterator{r: r, cur: r.Start, last: r.Start, n: 1}
+}
+
+// IterateTime returns an iterator over the moments of an hourly, minutely or cron series, as with NextTime.
+func (r Rule) IterateTime() *Iterator {
+	it := r.Iterate()
+	it.timed = true
+	return it
+}
+
+// Next moves on to the occurrence after the current one and returns it.
+// Past Until or the Count-th occurrence it returns ErrSeriesEnded and stays where it is.
+func (it *Iterator) Next() (time.Time, error) {
+	r := it.r
+	if r.Start.IsZero() {
+		return it.last, fmt.Errorf("the series has no start to iterate from: %s", r)
+	}
+	if it.timed && r.Shift != ShiftNone {
+		return it.last, fmt.Errorf("shift is not supported for rules with a time of day: %s", r)
+	}
+	// A shifted date may fall on or before the previous one, in which case the following candidate is taken
+	for {
+		if r.Count > 0 && it.n >= r.Count {
+			return it.last, ErrSeriesEnded
+		}
+		next, err := it.step()
+		if err != nil {
+			return it.last, err
+		}
+		if !r.Until.IsZero() && afterNow(next, r.Until) {
+			return it.last, ErrSeriesEnded
+		}
+		it.cur, it.n = next, it.n+1
+		if shifted := r.shift(next); it.timed || afterNow(shifted, it.last) {
+			it.last = shifted
+			return shifted, nil
+		}
+	}
+}
+
+// step returns the occurrence after the current one without the end conditions and shift.
+func (it *Iterator) step() (time.Time, error) {
+	r := it.r
+	if !it.timed {
+		return r.step(it.cur, it.cur)
+	}
+	switch r.Kind {
+	case Hourly, Minutely:
+		return it.cur.Add(r.timeStep()), nil
+	case Cron:
+		return r.cron.nextTime(it.cur)
+	}
+	return it.cur, fmt.Errorf("only hourly, minutely and cron rules have a time of day: %s", r)
+}
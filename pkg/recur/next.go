@@ -0,0 +1,453 @@
+package recur
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/holiday"
+)
+
+// Next returns the first date of the series that is after both the date of after and the date of Start.
+// Only dates matter: the result is at midnight and after is compared by its date alone.
+// An occurrence past Until or past the Count-th one, counting Start as the first, is reported as ErrSeriesEnded.
+// Hourly and minutely rules need NextTime.
+func (r Rule) Next(after time.Time) (time.Time, error) {
+	date := r.Start
+	if date.IsZero() {
+		date = after
+	}
+	now := after
+
+	// A shifted date may fall on or before the previous one, in which case the following candidate is taken
+	limit := now
+	if date.After(now) {
+		limit = date
+	}
+	for {
+		next, err := r.step(date, now)
+		if err != nil {
+			return date, err
+		}
+		if !r.Until.IsZero() && afterNow(next, r.Until) {
+			return date, ErrSeriesEnded
+		}
+		ended, err := r.pastCount(next)
+		if err != nil {
+			return date, err
+		}
+		if ended {
+			return date, ErrSeriesEnded
+		}
+		if shifted := r.shift(next); afterNow(shifted, limit) {
+			return shifted, nil
+		}
+		now = next
+	}
+}
+
+// shift moves the date off a day off as the Shift of the rule tells.
+func (r Rule) shift(date time.Time) time.Time {
+	switch r.Shift {
+	case ShiftNext:
+		return holiday.NextWorkday(date)
+	case ShiftPrev:
+		return holiday.PrevWorkday(date)
+	}
+	return date
+}
+
+// NextTime returns the first moment of an hourly, minutely or cron series that is after both after and Start.
+// Start supplies the time of day the hourly and minutely steps count from.
+func (r Rule) NextTime(after time.Time) (time.Time, error) {
+	start := r.Start
+	if start.IsZero() {
+		start = after
+	}
+	if r.Shift != ShiftNone {
+		return start, fmt.Errorf("shift is not supported for rules with a time of day: %s", r)
+	}
+	limit := after
+	if start.After(after) {
+		limit = start
+	}
+
+	var next time.Time
+	switch r.Kind {
+	case Hourly, Minutely:
+		step := r.timeStep()
+		// Jump over the whole steps between start and limit at once
+		n := limit.Sub(start)/step + 1
+		next = start.Add(n * step)
+		if r.Count > 0 && !r.Start.IsZero() && n >= time.Duration(r.Count) {
+			return start, ErrSeriesEnded
+		}
+	case Cron:
+		var err error
+		if next, err = r.cron.nextTime(limit); err != nil {
+			return start, err
+		}
+		if r.Count > 0 && !r.Start.IsZero() {
+			// Walk the occurrences from Start until one reaches next, which takes no more steps than Count
+			it := r.IterateTime()
+			for cur := start; cur.Before(next); {
+				if cur, err = it.Next(); err != nil {
+					return start, err
+				}
+			}
+		}
+	default:
+		return start, fmt.Errorf("only hourly, minutely and cron rules have a time of day: %s", r)
+	}
+	if !r.Until.IsZero() && afterNow(next, r.Until) {
+		return start, ErrSeriesEnded
+	}
+	return next, nil
+}
+
+// timeStep returns the step of an hourly or minutely rule.
+func (r Rule) timeStep() time.Duration {
+	if r.Kind == Minutely {
+		return time.Duration(r.Interval) * time.Minute
+	}
+	return time.Duration(r.Interval) * time.Hour
+}
+
+// HasTime reports whether the rule moves the time of day, which is the case for hourly, minutely and cron rules.
+func (r Rule) HasTime() bool {
+	return r.Kind == Hourly || r.Kind == Minutely || r.Kind == Cron
+}
+
+// pastCount reports whether the occurrence on date comes after the Count-th one of the series, counting Start
+// as the first. Daily and yearly rules are counted at once, the others occurrence by occurrence, which takes
+// no more steps than Count. RRULE counts COUNT itself, and a rule without Start has nothing to count from.
+func (r Rule) pastCount(date time.Time) (bool, error) {
+	if r.Count == 0 || r.Kind == RRule || r.Start.IsZero() {
+		return false, nil
+	}
+	switch r.Kind {
+	case Daily:
+		return (dayNumber(date)-dayNumber(r.Start))/r.Interval >= r.Count, nil
+	case Yearly:
+		return (date.Year()-r.Start.Year())/r.Interval >= r.Count, nil
+	}
+	cur := r.Start
+	for n := 1; n < r.Count && afterNow(date, cur); n++ {
+		var err error
+		if cur, err = r.step(cur, cur); err != nil {
+			return false, err
+		}
+	}
+	return afterNow(date, cur), nil
+}
+
+// step moves the date to the next occurrence after now without the end conditions and shift.
+func (r Rule) step(date, now time.Time) (time.Time, error) {
+	switch r.Kind {
+	case Workdays:
+		return addWorkdays(date, now, r.Interval), nil
+	case Daily:
+		return addDay(date, now, r.Interval), nil
+	case Yearly:
+		return addYear(date, now, r.Interval), nil
+	case Weekly:
+		return addWeek(date, now, r.Weekdays, r.Interval)
+	case Monthly:
+		return addMonth(date, now, r.Days, r.Months)
+	case MonthWeekday:
+		return addMonthWeekday(date, now, r.Nth, r.Months)
+	case WeekNumber:
+		return addWeekNumber(date, now, r.Weeks, r.Weekdays)
+	case Quarterly:
+		return addQuarter(date, now, r.Days)
+	case Cron:
+		return addCron(date, now, r.cron)
+	case RRule:
+		return addRRule(date, now, r.rrule)
+	case Hourly, Minutely:
+		return date, fmt.Errorf("hourly and minutely repeats require a task time: %s", r)
+	}
+	return date, fmt.Errorf("unsupported repeat type: %s", r.Kind)
+}
+
+// afterNow checks if the date is after the current time.
+func afterNow(date, now time.Time) bool {
+	dy, dm, dd := date.Date()
+	ny, nm, nd := now.Date()
+
+	if dy != ny {
+		return dy > ny
+	}
+
+	if dm != nm {
+		return dm > nm
+	}
+
+	return dd > nd
+}
+
+// dayNumber returns the number of days from 1970-01-01 to the date of t, ignoring its clock and time zone.
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// stepsAfter returns the smallest number of steps of size interval that takes a date
+// past another one gap units later.
+func stepsAfter(gap, interval int) int {
+	if gap < 0 {
+		return 1
+	}
+	return gap/interval + 1
+}
+
+// dayAfter returns the day after the later of date and now, keeping the clock of date.
+func dayAfter(date, now time.Time) time.Time {
+	return date.AddDate(0, 0, max(dayNumber(now)-dayNumber(date), 0)+1)
+}
+
+// addDay adds the smallest multiple of interval days to the date that takes it after now.
+func addDay(date, now time.Time, interval int) time.Time {
+	k := stepsAfter(dayNumber(now)-dayNumber(date), interval)
+	return date.AddDate(0, 0, k*interval)
+}
+
+// addWorkdays adds the smallest multiple of interval working days to the date that takes it after now.
+// The working days up to now are counted at once, only the last step is walked day by day.
+func addWorkdays(date, now time.Time, interval int) time.Time {
+	passed := 0
+	if afterNow(now, date) {
+		passed = holiday.CountWorkdays(date, now)
+		date = date.AddDate(0, 0, dayNumber(now)-dayNumber(date))
+	}
+	k := stepsAfter(passed, interval)
+	return holiday.AddWorkdays(date, k*interval-passed)
+}
+
+// addYear adds the smallest multiple of interval years to the date that takes it after now.
+func addYear(date, now time.Time, interval int) time.Time {
+	k := stepsAfter(now.Year()-date.Year()-1, interval)
+	for {
+		next := addYears(date, k*interval, interval)
+		if afterNow(next, now) {
+			return next
+		}
+		k++
+	}
+}
+
+// addYears adds n years to the date in steps of interval years. As with repeated AddDate calls,
+// February 29 turns into March 1 for good once a step lands on a common year.
+func addYears(date time.Time, n, interval int) time.Time {
+	next := date.AddDate(n, 0, 0)
+	if date.Month() != time.February || date.Day() != 29 || next.Day() != 29 {
+		return next
+	}
+	// The first step lands on a common year unless the interval is a multiple of 4
+	if interval%4 != 0 {
+		return next.AddDate(0, 0, 1)
+	}
+	// Otherwise every step is a multiple of 4 and only the centuries not divisible by 400 are common years
+	y := date.Year()
+	for c := (y/100 + 1) * 100; c <= y+n; c += 100 {
+		if c%400 != 0 && (c-y)%interval == 0 {
+			return next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// addWeek finds the next date on one of the given days of the week.
+// With weeks > 1 only every weeks-th week counts, starting from the week (Monday to Sunday) of the date.
+func addWeek(date, now time.Time, weekdays []time.Weekday, weeks int) (time.Time, error) {
+	anchor := date.AddDate(0, 0, -(int(date.Weekday())+6)%7) // Monday of the start week
+
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+	// Jump to the Monday of the next week in the cycle unless this week is one
+	if skip := weekNumber(anchor, date) % weeks; skip != 0 {
+		date = anchor.AddDate(0, 0, 7*(weekNumber(anchor, date)+weeks-skip))
+	}
+
+	for i := 0; i < 7*weeks; i++ {
+		candidate := date.AddDate(0, 0, i)
+		if !slices.Contains(weekdays, candidate.Weekday()) {
+			continue
+		}
+		if weekNumber(anchor, candidate)%weeks == 0 {
+			return candidate, nil
+		}
+	}
+	return date, fmt.Errorf("no valid days of the week provided")
+}
+
+// weekNumber returns the number of whole weeks from the Monday anchor to the week of date.
+func weekNumber(anchor, date time.Time) int {
+	days := int(date.Sub(anchor).Hours()+12) / 24 // Round to whole days in case of DST changes
+	return days / 7
+}
+
+// addMonth finds the next date on one of the given days of the month, within the given months if any.
+func addMonth(date, now time.Time, days []int, months []time.Month) (time.Time, error) {
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+
+	for i := 0; i < 24; i++ {
+		nextMonth := date.AddDate(0, i, 0)
+		if len(months) > 0 && !slices.Contains(months, nextMonth.Month()) {
+			continue
+		}
+		lastDay := lastDayOfMonth(nextMonth)
+
+		var candidates []int
+		for _, day := range days {
+			switch {
+			case day > 0 && day <= lastDay:
+				candidates = append(candidates, day)
+			case day == -1:
+				candidates = append(candidates, lastDay)
+			case day == -2 && lastDay > 1:
+				candidates = append(candidates, lastDay-1)
+			}
+		}
+
+		sort.Ints(candidates) // Sort candidates to find the next valid date
+
+		for _, d := range candidates {
+			candidateDate := time.Date(nextMonth.Year(), nextMonth.Month(), d, 0, 0, 0, 0, date.Location())
+			if candidateDate.After(date) {
+				return candidateDate, nil
+			}
+		}
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+func lastDayOfMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// addMonthWeekday finds the next date matching an nth-weekday-of-month rule,
+// within the given months if any.
+func addMonthWeekday(date, now time.Time, rules []NthWeekday, months []time.Month) (time.Time, error) {
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+
+	// A fifth weekday may be missing for years in a row, so look further ahead than addMonth does
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	for i := 0; i < 12*30; i++ {
+		month := first.AddDate(0, i, 0)
+		if len(months) > 0 && !slices.Contains(months, month.Month()) {
+			continue
+		}
+		lastDay := lastDayOfMonth(month)
+
+		var candidates []int
+		for _, rule := range rules {
+			if d := nthWeekdayOfMonth(month, rule.N, rule.Weekday, lastDay); d > 0 {
+				candidates = append(candidates, d)
+			}
+		}
+
+		sort.Ints(candidates) // Sort candidates to find the next valid date
+
+		for _, d := range candidates {
+			candidateDate := time.Date(month.Year(), month.Month(), d, 0, 0, 0, 0, date.Location())
+			if !candidateDate.Before(date) {
+				return candidateDate, nil
+			}
+		}
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+// addWeekNumber finds the next date on one of the given days of the week in one of the given ISO weeks.
+func addWeekNumber(date, now time.Time, weeks []int, weekdays []time.Weekday) (time.Time, error) {
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+
+	// Week 53 may be missing for up to six years in a row
+	year, _ := date.ISOWeek()
+	for y := year; y <= year+7; y++ {
+		var candidates []time.Time
+		for _, week := range weeks {
+			for _, wd := range weekdays {
+				candidate := isoWeekDate(y, week, wd, date.Location())
+				if _, w := candidate.ISOWeek(); w == week {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+
+		slices.SortFunc(candidates, time.Time.Compare) // Sort candidates to find the next valid date
+
+		for _, candidateDate := range candidates {
+			if dayNumber(candidateDate) >= dayNumber(date) {
+				return candidateDate, nil
+			}
+		}
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+// isoWeekDate returns the date of the day of the week in the given week of the ISO year.
+// Week 53 of a year that has only 52 weeks falls into week 1 of the next year.
+func isoWeekDate(year, week int, wd time.Weekday, loc *time.Location) time.Time {
+	// January 4 is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, 1-isoWeekday(jan4.Weekday()))
+	return monday.AddDate(0, 0, 7*(week-1)+isoWeekday(wd)-1)
+}
+
+// addQuarter finds the next date on one of the given days of the quarter,
+// negative days are counted from the end of the quarter.
+func addQuarter(date, now time.Time, days []int) (time.Time, error) {
+	// If the date is before now, set it to now
+	date = dayAfter(date, now)
+
+	first := time.Date(date.Year(), (date.Month()-1)/3*3+1, 1, 0, 0, 0, 0, date.Location())
+	// Every day of the quarter exists within a year
+	for i := 0; i < 5; i++ {
+		quarter := first.AddDate(0, 3*i, 0)
+		length := dayNumber(quarter.AddDate(0, 3, 0)) - dayNumber(quarter)
+
+		var candidates []int
+		for _, day := range days {
+			switch {
+			case day > 0 && day <= length:
+				candidates = append(candidates, day)
+			case day < 0 && -day <= length:
+				candidates = append(candidates, length+day+1)
+			}
+		}
+
+		sort.Ints(candidates) // Sort candidates to find the next valid date
+
+		for _, d := range candidates {
+			candidateDate := quarter.AddDate(0, 0, d-1)
+			if dayNumber(candidateDate) >= dayNumber(date) {
+				return candidateDate, nil
+			}
+		}
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+// nthWeekdayOfMonth returns the day of the month of the nth weekday in the month of t,
+// counting from the end when n is negative. It returns 0 if the month has no such day.
+func nthWeekdayOfMonth(t time.Time, n int, weekday time.Weekday, lastDay int) int {
+	if n > 0 {
+		firstWeekday := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Weekday()
+		day := 1 + (int(weekday)-int(firstWeekday)+7)%7 + (n-1)*7
+		if day > lastDay {
+			return 0
+		}
+		return day
+	}
+	lastWeekday := time.Date(t.Year(), t.Month(), lastDay, 0, 0, 0, 0, t.Location()).Weekday()
+	day := lastDay - (int(lastWeekday)-int(weekday)+7)%7 + (n+1)*7
+	if day < 1 {
+		return 0
+	}
+	return day
+}
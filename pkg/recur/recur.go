@@ -0,0 +1,438 @@
+// Package recur parses and evaluates the repeat rules of tasks, such as "d 7", "w 1,5 /2",
+// "m 1,-1 2,8", "mw -1:5", "wn 1,14,27,40 1", "q -1", "cron 0 9 * * 1-5" or "RRULE:FREQ=MONTHLY;BYDAY=-1FR",
+// optionally followed by the "until YYYYMMDD", "count N" and "shift next|prev" modifiers.
+package recur
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const formatDate = "20060102" // Format for date in YYYYMMDD format
+
+const maxCount = 10000 // maxCount caps the count of a rule, which bounds the walks over a limited series
+
+// Kind is the type of a repeat rule, the first word of its string form.
+type Kind string
+
+const (
+	Daily        Kind = "d"     // every N days
+	Workdays     Kind = "bd"    // every N working days of the holiday calendar
+	Weekly       Kind = "w"     // on the given days of every N-th week
+	Monthly      Kind = "m"     // on the given days of the month
+	MonthWeekday Kind = "mw"    // on the n-th weekday of the month
+	WeekNumber   Kind = "wn"    // on the given days of the week of the listed ISO weeks
+	Quarterly    Kind = "q"     // on the given days of the quarter
+	Yearly       Kind = "y"     // every N years
+	Hourly       Kind = "h"     // every N hours, tasks with a time of day only
+	Minutely     Kind = "min"   // every N minutes, tasks with a time of day only
+	Cron         Kind = "cron"  // a five-field cron expression
+	RRule        Kind = "RRULE" // an RFC 5545 recurrence rule
+)
+
+// Shift tells where to move an occurrence that falls on a day off.
+type Shift int
+
+const (
+	ShiftNone Shift = iota // ShiftNone keeps dates that fall on days off
+	ShiftNext              // ShiftNext moves a date off a day off to the next working day
+	ShiftPrev              // ShiftPrev moves a date off a day off to the previous working day
+)
+
+// ErrSeriesEnded is returned when a rule has no occurrences left because of its until date or count.
+var ErrSeriesEnded = errors.New("no more occurrences for the repeat rule")
+
+// Field names the part of a rule an Error refers to.
+type Field string
+
+const (
+	FieldRule     Field = "rule"     // the rule as a whole, e.g. an unknown kind or a wrong number of parts
+	FieldInterval Field = "interval" // the interval of d, bd, y, h, min and the week interval of w
+	FieldWeekday  Field = "weekday"  // a day of the week of w, mw and wn
+	FieldWeek     Field = "week"     // the week number of mw and the ISO week of wn
+	FieldDay      Field = "day"      // a day of the month of m or of the quarter of q
+	FieldMonth    Field = "month"    // a month of m and mw
+	FieldCron     Field = "cron"     // the cron expression
+	FieldRRule    Field = "rrule"    // the RFC 5545 rule
+	FieldUntil    Field = "until"    // the until modifier
+	FieldCount    Field = "count"    // the count modifier
+	FieldShift    Field = "shift"    // the shift modifier
+)
+
+// Error reports an invalid repeat rule.
+type Error struct {
+	Rule   string // Rule is the repeat string being parsed
+	Field  Field  // Field is the part of the rule that is wrong
+	Value  string // Value is the offending value
+	Reason string // Reason explains what is expected, may be empty
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("invalid %s in repeat rule %q: %s", e.Field, e.Rule, e.Value)
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	return msg
+}
+
+// NthWeekday is an entry of a "mw" rule: the N-th weekday of the month,
+// N is 1..5 counted from the start of the month or -1..-5 counted from the end.
+type NthWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed repeat rule. Rules are created by Parse; the cron and RRULE kinds
+// cannot be built by hand because their expressions are kept in unexported fields.
+type Rule struct {
+	Kind     Kind
+	Interval int            // Interval is the step of d, bd, y, h and min, and every Interval-th week for w
+	Weekdays []time.Weekday // Weekdays are the days of a w or wn rule
+	Weeks    []int          // Weeks are the ISO week numbers of a wn rule
+	Days     []int          // Days are the days of the month of a m rule or of the quarter of a q rule, -1 is the last day
+	Nth      []NthWeekday   // Nth are the entries of a mw rule
+	Months   []time.Month   // Months restrict m and mw rules to the listed months, empty means every month
+
+	Until time.Time // Until is the last date the rule may produce, zero means no limit
+	Count int       // Count is the total number of occurrences, 0 means no limit
+	Shift Shift     // Shift moves occurrences that fall on days off
+
+	// Start is the first occurrence the series counts from, e.g. the date of the task.
+	// The zero time makes Next count from the moment it is given.
+	Start time.Time
+
+	cron  *cronSchedule
+	rrule *rrule
+}
+
+// Parse parses a repeat rule. Invalid rules are reported as *Error.
+func Parse(repeat string) (Rule, error) {
+	var r Rule
+	arr := strings.Fields(repeat)
+	if len(arr) == 0 {
+		return r, &Error{Rule: repeat, Field: FieldRule, Value: repeat, Reason: "cannot be empty"}
+	}
+
+	// Take the modifiers off the end of the rule
+	var hasUntil, hasCount, hasShift bool
+	for len(arr) > 2 {
+		key, value := arr[len(arr)-2], arr[len(arr)-1]
+		switch {
+		case key == "until" && !hasUntil:
+			until, err := time.Parse(formatDate, value)
+			if err != nil {
+				return r, &Error{Rule: repeat, Field: FieldUntil, Value: value, Reason: "expected YYYYMMDD"}
+			}
+			r.Until, hasUntil = until, true
+		case key == "count" && !hasCount:
+			count, err := strconv.Atoi(value)
+			if err != nil || count <= 0 || count > maxCount {
+				return r, &Error{Rule: repeat, Field: FieldCount, Value: value,
+					Reason: fmt.Sprintf("expected a positive number up to %d", maxCount)}
+			}
+			r.Count, hasCount = count, true
+		case key == "shift" && !hasShift:
+			switch value {
+			case "next":
+				r.Shift = ShiftNext
+			case "prev":
+				r.Shift = ShiftPrev
+			default:
+				return r, &Error{Rule: repeat, Field: FieldShift, Value: value, Reason: "expected next or prev"}
+			}
+			hasShift = true
+		case key == "until" || key == "count" || key == "shift":
+			return r, &Error{Rule: repeat, Field: Field(key), Value: value, Reason: "duplicate modifier"}
+		default:
+			return parseBody(r, repeat, arr, hasUntil || hasCount)
+		}
+		arr = arr[:len(arr)-2]
+	}
+	return parseBody(r, repeat, arr, hasUntil || hasCount)
+}
+
+// parseBody completes the rule with its modifiers already set from the rest of the repeat string.
+func parseBody(r Rule, repeat string, arr []string, hasEnd bool) (Rule, error) {
+	if err := r.parseBody(repeat, arr, hasEnd); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+// parseBody parses the rule without its modifiers, e.g. ["w", "1,5", "/2"].
+func (r *Rule) parseBody(repeat string, arr []string, hasEnd bool) error {
+	fail := func(field Field, value, reason string) error {
+		return &Error{Rule: repeat, Field: field, Value: value, Reason: reason}
+	}
+	args := arr[1:]
+	if strings.HasPrefix(strings.ToUpper(arr[0]), rrulePrefix) {
+		if len(args) > 0 {
+			return fail(FieldRule, strings.Join(arr, " "), "an RRULE cannot contain spaces")
+		}
+		if hasEnd {
+			return fail(FieldRule, repeat, "use COUNT and UNTIL inside the RRULE")
+		}
+		rule, err := parseRRule(arr[0])
+		if err != nil {
+			return fail(FieldRRule, arr[0], err.Error())
+		}
+		r.Kind, r.rrule, r.Interval = RRule, rule, rule.interval
+		r.Until, r.Count = rule.until, rule.count
+		return nil
+	}
+
+	r.Kind = Kind(arr[0])
+	switch r.Kind {
+	case Daily, Workdays, Yearly, Hourly, Minutely:
+		maxInterval := map[Kind]int{Daily: 399, Workdays: 399, Yearly: 100, Hourly: 399, Minutely: 24 * 60}[r.Kind]
+		r.Interval = 1
+		if len(args) > 1 || (len(args) == 0 && r.Kind != Yearly) {
+			return fail(FieldRule, strings.Join(arr, " "), "expected "+string(r.Kind)+" <interval>")
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 || n > maxInterval {
+				return fail(FieldInterval, args[0], fmt.Sprintf("expected 1 to %d", maxInterval))
+			}
+			r.Interval = n
+		}
+
+	case Weekly:
+		if len(args) == 0 || len(args) > 2 {
+			return fail(FieldRule, strings.Join(arr, " "), "expected w <days> [/<weeks>]")
+		}
+		for _, v := range strings.Split(args[0], ",") {
+			day, err := strconv.Atoi(v)
+			if err != nil || day < 1 || day > 7 {
+				return fail(FieldWeekday, v, "expected 1 (Monday) to 7 (Sunday)")
+			}
+			if wd := time.Weekday(day % 7); !slices.Contains(r.Weekdays, wd) {
+				r.Weekdays = append(r.Weekdays, wd)
+			}
+		}
+		r.Interval = 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(strings.TrimPrefix(args[1], "/"))
+			if err != nil || !strings.HasPrefix(args[1], "/") || n <= 0 || n > 52 {
+				return fail(FieldInterval, args[1], "expected /1 to /52")
+			}
+			r.Interval = n
+		}
+
+	case Monthly, MonthWeekday:
+		if len(args) == 0 || len(args) > 2 {
+			return fail(FieldRule, strings.Join(arr, " "), "expected "+string(r.Kind)+" <days> [<months>]")
+		}
+		if len(args) == 2 {
+			for _, v := range strings.Split(args[1], ",") {
+				m, err := strconv.Atoi(v)
+				if err != nil || m < 1 || m > 12 {
+					return fail(FieldMonth, v, "expected 1 to 12")
+				}
+				if !slices.Contains(r.Months, time.Month(m)) {
+					r.Months = append(r.Months, time.Month(m))
+				}
+			}
+		}
+		for _, v := range strings.Split(args[0], ",") {
+			if r.Kind == Monthly {
+				day, err := strconv.Atoi(v)
+				if err != nil || day < -2 || day > 31 || day == 0 {
+					return fail(FieldDay, v, "expected 1 to 31, -1 or -2")
+				}
+				if !slices.Contains(r.Days, day) {
+					r.Days = append(r.Days, day)
+				}
+				continue
+			}
+			week, day, ok := strings.Cut(v, ":")
+			if !ok {
+				return fail(FieldRule, v, "expected <week>:<weekday>")
+			}
+			n, err := strconv.Atoi(week)
+			if err != nil || n < -5 || n > 5 || n == 0 {
+				return fail(FieldWeek, v, "expected 1 to 5 or -1 to -5")
+			}
+			wd, err := strconv.Atoi(day)
+			if err != nil || wd < 1 || wd > 7 {
+				return fail(FieldWeekday, v, "expected 1 (Monday) to 7 (Sunday)")
+			}
+			if nth := (NthWeekday{N: n, Weekday: time.Weekday(wd % 7)}); !slices.Contains(r.Nth, nth) {
+				r.Nth = append(r.Nth, nth)
+			}
+		}
+
+	case WeekNumber:
+		if len(args) == 0 || len(args) > 2 {
+			return fail(FieldRule, strings.Join(arr, " "), "expected wn <weeks> [<weekdays>]")
+		}
+		for _, v := range strings.Split(args[0], ",") {
+			week, err := strconv.Atoi(v)
+			if err != nil || week < 1 || week > 53 {
+				return fail(FieldWeek, v, "expected 1 to 53")
+			}
+			if !slices.Contains(r.Weeks, week) {
+				r.Weeks = append(r.Weeks, week)
+			}
+		}
+		days := "1" // Monday by default
+		if len(args) == 2 {
+			days = args[1]
+		}
+		for _, v := range strings.Split(days, ",") {
+			day, err := strconv.Atoi(v)
+			if err != nil || day < 1 || day > 7 {
+				return fail(FieldWeekday, v, "expected 1 (Monday) to 7 (Sunday)")
+			}
+			if wd := time.Weekday(day % 7); !slices.Contains(r.Weekdays, wd) {
+				r.Weekdays = append(r.Weekdays, wd)
+			}
+		}
+
+	case Quarterly:
+		if len(args) != 1 {
+			return fail(FieldRule, strings.Join(arr, " "), "expected q <days>")
+		}
+		for _, v := range strings.Split(args[0], ",") {
+			day, err := strconv.Atoi(v)
+			if err != nil || day < -92 || day > 92 || day == 0 {
+				return fail(FieldDay, v, "expected 1 to 92 or -1 to -92")
+			}
+			if !slices.Contains(r.Days, day) {
+				r.Days = append(r.Days, day)
+			}
+		}
+
+	case Cron:
+		c, err := parseCron(args)
+		if err != nil {
+			return fail(FieldCron, strings.Join(args, " "), err.Error())
+		}
+		r.cron = c
+
+	default:
+		return fail(FieldRule, arr[0], "unsupported repeat type")
+	}
+	return nil
+}
+
+// String returns the canonical form of the rule, which Parse accepts and turns into the same rule.
+// Lists are sorted and deduplicated, and defaults such as "/1" are left out.
+func (r Rule) String() string {
+	parts := []string{string(r.Kind)}
+	switch r.Kind {
+	case Daily, Workdays, Hourly, Minutely:
+		parts = append(parts, strconv.Itoa(r.Interval))
+	case Yearly:
+		if r.Interval > 1 {
+			parts = append(parts, strconv.Itoa(r.Interval))
+		}
+	case Weekly:
+		days := make([]int, len(r.Weekdays))
+		for i, wd := range r.Weekdays {
+			days[i] = isoWeekday(wd)
+		}
+		parts = append(parts, joinInts(sortedUnique(days)))
+		if r.Interval > 1 {
+			parts = append(parts, "/"+strconv.Itoa(r.Interval))
+		}
+	case Monthly, Quarterly:
+		days := slices.Clone(r.Days)
+		slices.SortFunc(days, compareDays)
+		parts = append(parts, joinInts(slices.Compact(days)))
+		parts = appendMonths(parts, r.Months)
+	case WeekNumber:
+		parts = append(parts, joinInts(sortedUnique(r.Weeks)))
+		if !slices.Equal(r.Weekdays, []time.Weekday{time.Monday}) {
+			days := make([]int, len(r.Weekdays))
+			for i, wd := range r.Weekdays {
+				days[i] = isoWeekday(wd)
+			}
+			parts = append(parts, joinInts(sortedUnique(days)))
+		}
+	case MonthWeekday:
+		nth := slices.Clone(r.Nth)
+		slices.SortFunc(nth, func(a, b NthWeekday) int {
+			if a.N != b.N {
+				return compareDays(a.N, b.N)
+			}
+			return isoWeekday(a.Weekday) - isoWeekday(b.Weekday)
+		})
+		entries := make([]string, 0, len(nth))
+		for _, e := range slices.Compact(nth) {
+			entries = append(entries, fmt.Sprintf("%d:%d", e.N, isoWeekday(e.Weekday)))
+		}
+		parts = append(parts, strings.Join(entries, ","))
+		parts = appendMonths(parts, r.Months)
+	case Cron:
+		if r.cron != nil {
+			parts = append(parts, r.cron.expr)
+		}
+	case RRule:
+		if r.rrule != nil {
+			parts = []string{r.rrule.String()}
+		}
+	}
+
+	if r.Kind != RRule {
+		if !r.Until.IsZero() {
+			parts = append(parts, "until", r.Until.Format(formatDate))
+		}
+		if r.Count > 0 {
+			parts = append(parts, "count", strconv.Itoa(r.Count))
+		}
+	}
+	switch r.Shift {
+	case ShiftNext:
+		parts = append(parts, "shift", "next")
+	case ShiftPrev:
+		parts = append(parts, "shift", "prev")
+	}
+	return strings.Join(parts, " ")
+}
+
+// compareDays orders days counted from the start before the ones counted from the end,
+// the latter from the last one back: 1, 15, -1, -2.
+func compareDays(a, b int) int {
+	if (a < 0) != (b < 0) || a < 0 {
+		return b - a
+	}
+	return a - b
+}
+
+// isoWeekday numbers the days of the week from 1 (Monday) to 7 (Sunday).
+func isoWeekday(wd time.Weekday) int {
+	if wd == time.Sunday {
+		return 7
+	}
+	return int(wd)
+}
+
+func appendMonths(parts []string, months []time.Month) []string {
+	if len(months) == 0 {
+		return parts
+	}
+	values := make([]int, len(months))
+	for i, m := range months {
+		values[i] = int(m)
+	}
+	return append(parts, joinInts(sortedUnique(values)))
+}
+
+func sortedUnique(values []int) []int {
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
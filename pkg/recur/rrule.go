@@ -0,0 +1,415 @@
+package recur
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const rrulePrefix = "RRULE:" // rrulePrefix marks a repeat pattern written as an RFC 5545 recurrence rule
+
+// rruleFreq is the FREQ part of a recurrence rule.
+type rruleFreq int
+
+const (
+	freqDaily rruleFreq = iota
+	freqWeekly
+	freqMonthly
+	freqYearly
+)
+
+// rruleWeekdays maps the two-letter RFC 5545 weekday codes to time.Weekday.
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// rruleDay is a BYDAY entry, e.g. "MO" (n == 0), "2TU" or "-1FR".
+type rruleDay struct {
+	n       int
+	weekday time.Weekday
+}
+
+// rrule holds a parsed RFC 5545 recurrence rule. Only date-level parts are supported.
+type rrule struct {
+	freq       rruleFreq
+	interval   int
+	byDay      []rruleDay
+	byMonthDay []int
+	byMonth    map[time.Month]bool
+	bySetPos   []int
+	count      int
+	until      time.Time
+	wkst       time.Weekday
+}
+
+// parseRRule parses a string like "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=10".
+func parseRRule(repeat string) (*rrule, error) {
+	rule := &rrule{interval: 1, wkst: time.Monday}
+	var hasFreq, hasUntil bool
+
+	body := strings.TrimSpace(repeat[len(rrulePrefix):])
+	if body == "" {
+		return nil, fmt.Errorf("empty RRULE: %s", repeat)
+	}
+	for _, part := range strings.Split(body, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid RRULE part: %s", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		var err error
+		switch key {
+		case "FREQ":
+			hasFreq = true
+			switch value {
+			case "DAILY":
+				rule.freq = freqDaily
+			case "WEEKLY":
+				rule.freq = freqWeekly
+			case "MONTHLY":
+				rule.freq = freqMonthly
+			case "YEARLY":
+				rule.freq = freqYearly
+			default:
+				return nil, fmt.Errorf("unsupported RRULE frequency: %s", value)
+			}
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err != nil || rule.interval <= 0 {
+				return nil, fmt.Errorf("invalid RRULE interval: %s", value)
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+			if err != nil || rule.count <= 0 || rule.count > maxCount {
+				return nil, fmt.Errorf("invalid RRULE count, expected a positive number up to %d: %s", maxCount, value)
+			}
+		case "UNTIL":
+			hasUntil = true
+			// Only the date part matters, so a trailing time such as T235959Z is ignored
+			date, _, _ := strings.Cut(value, "T")
+			rule.until, err = time.Parse(formatDate, date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE until: %s", value)
+			}
+		case "WKST":
+			wd, ok := rruleWeekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid RRULE week start: %s", value)
+			}
+			rule.wkst = wd
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("invalid RRULE day: %s", v)
+				}
+				wd, ok := rruleWeekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid RRULE day: %s", v)
+				}
+				day := rruleDay{weekday: wd}
+				if ord := v[:len(v)-2]; ord != "" {
+					day.n, err = strconv.Atoi(ord)
+					if err != nil || day.n == 0 || day.n < -53 || day.n > 53 {
+						return nil, fmt.Errorf("invalid RRULE day: %s", v)
+					}
+				}
+				rule.byDay = append(rule.byDay, day)
+			}
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseRRuleInts(value, 31)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE month day: %s", value)
+			}
+		case "BYMONTH":
+			months, err := parseRRuleInts(value, 12)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE month: %s", value)
+			}
+			rule.byMonth = make(map[time.Month]bool)
+			for _, m := range months {
+				if m < 0 {
+					return nil, fmt.Errorf("invalid RRULE month: %s", value)
+				}
+				rule.byMonth[time.Month(m)] = true
+			}
+		case "BYSETPOS":
+			rule.bySetPos, err = parseRRuleInts(value, 366)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE set position: %s", value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part: %s", key)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("RRULE must contain FREQ: %s", repeat)
+	}
+	if hasUntil && rule.count > 0 {
+		return nil, fmt.Errorf("RRULE cannot contain both COUNT and UNTIL: %s", repeat)
+	}
+	if rule.freq == freqDaily || rule.freq == freqWeekly {
+		for _, d := range rule.byDay {
+			if d.n != 0 {
+				return nil, fmt.Errorf("numeric BYDAY values are only allowed for MONTHLY and YEARLY rules: %s", repeat)
+			}
+		}
+	}
+	if len(rule.bySetPos) > 0 && len(rule.byDay) == 0 && len(rule.byMonthDay) == 0 && rule.byMonth == nil {
+		return nil, fmt.Errorf("BYSETPOS requires another BYxxx part: %s", repeat)
+	}
+	return rule, nil
+}
+
+// String returns the canonical form of the rule, e.g. "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=10".
+func (r *rrule) String() string {
+	parts := []string{"FREQ=" + [...]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}[r.freq]}
+	if r.interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval))
+	}
+	if r.byMonth != nil {
+		var months []int
+		for m := range r.byMonth {
+			months = append(months, int(m))
+		}
+		parts = append(parts, "BYMONTH="+joinInts(sortedUnique(months)))
+	}
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.byMonthDay))
+	}
+	if len(r.byDay) > 0 {
+		days := make([]string, len(r.byDay))
+		for i, d := range r.byDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.bySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.bySetPos))
+	}
+	if r.wkst != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.wkst))
+	}
+	if r.count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.Format(formatDate))
+	}
+	return rrulePrefix + strings.Join(parts, ";")
+}
+
+// String returns the BYDAY entry as written in a rule, e.g. "MO" or "-1FR".
+func (d rruleDay) String() string {
+	if d.n == 0 {
+		return weekdayCode(d.weekday)
+	}
+	return strconv.Itoa(d.n) + weekdayCode(d.weekday)
+}
+
+// weekdayCode returns the two-letter RFC 5545 code of the day of the week.
+func weekdayCode(wd time.Weekday) string {
+	for code, v := range rruleWeekdays {
+		if v == wd {
+			return code
+		}
+	}
+	return ""
+}
+
+// parseRRuleInts parses a comma-separated list of non-zero integers within [-limit, limit].
+func parseRRuleInts(value string, limit int) ([]int, error) {
+	var res []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < -limit || n > limit {
+			return nil, fmt.Errorf("value out of range: %d", n)
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// addRRule returns the first occurrence of the rule that is after both the start date and now.
+// The start date is used as DTSTART, so COUNT is counted from the date currently stored in the task.
+// A rule with COUNT walks its periods from DTSTART one by one, as the occurrences per period vary;
+// the walk stops once COUNT occurrences have passed, so it takes about COUNT steps at most.
+func addRRule(date, now time.Time, rule *rrule) (time.Time, error) {
+	limit := now
+	if date.After(now) {
+		limit = date
+	}
+
+	var found int
+	periodStart := rule.periodStart(date)
+	// Without COUNT nothing needs counting, so jump to the period before the one of limit at once
+	if rule.count == 0 {
+		if skip := rule.periodsBetween(periodStart, limit)/rule.interval - 1; skip > 0 {
+			periodStart = rule.addPeriods(periodStart, skip*rule.interval)
+		}
+	}
+	// Stop looking for occurrences that never come, e.g. BYMONTH=2;BYMONTHDAY=30
+	horizon := limit.AddDate(100, 0, 0)
+	for !periodStart.After(horizon) {
+		for _, d := range rule.expand(periodStart, date) {
+			if d.Before(date) {
+				continue
+			}
+			if !rule.until.IsZero() && afterNow(d, rule.until) {
+				return date, ErrSeriesEnded
+			}
+			found++
+			if rule.count > 0 && found > rule.count {
+				return date, ErrSeriesEnded
+			}
+			if afterNow(d, limit) {
+				return d, nil
+			}
+		}
+		periodStart = rule.addPeriods(periodStart, rule.interval)
+	}
+	return date, fmt.Errorf("cannot find suitable date for given rules")
+}
+
+// periodStart returns the first day of the FREQ period that contains date.
+func (r *rrule) periodStart(date time.Time) time.Time {
+	switch r.freq {
+	case freqWeekly:
+		offset := (int(date.Weekday()) - int(r.wkst) + 7) % 7
+		return date.AddDate(0, 0, -offset)
+	case freqMonthly:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	case freqYearly:
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, date.Location())
+	}
+	return date
+}
+
+// periodsBetween returns the number of whole FREQ periods from the period starting at start to the one containing t.
+func (r *rrule) periodsBetween(start, t time.Time) int {
+	switch r.freq {
+	case freqWeekly:
+		return (dayNumber(t) - dayNumber(start)) / 7
+	case freqMonthly:
+		return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	case freqYearly:
+		return t.Year() - start.Year()
+	}
+	return dayNumber(t) - dayNumber(start)
+}
+
+// addPeriods returns the first day of the period n periods after start.
+func (r *rrule) addPeriods(start time.Time, n int) time.Time {
+	switch r.freq {
+	case freqWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case freqMonthly:
+		return start.AddDate(0, n, 0)
+	case freqYearly:
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, 0, n)
+}
+
+// expand returns the sorted occurrences within the period starting at start.
+// dtstart supplies the defaults for rules that leave the day, weekday or month unspecified.
+func (r *rrule) expand(start, dtstart time.Time) []time.Time {
+	end := r.addPeriods(start, 1)
+
+	byMonthDay := r.byMonthDay
+	byDay := r.byDay
+	byMonth := r.byMonth
+	if len(byDay) == 0 && len(byMonthDay) == 0 {
+		switch r.freq {
+		case freqWeekly:
+			byDay = []rruleDay{{weekday: dtstart.Weekday()}}
+		case freqMonthly:
+			byMonthDay = []int{dtstart.Day()}
+		case freqYearly:
+			byMonthDay = []int{dtstart.Day()}
+			if byMonth == nil {
+				byMonth = map[time.Month]bool{dtstart.Month(): true}
+			}
+		}
+	}
+
+	var set []time.Time
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		if byMonth != nil && !byMonth[d.Month()] {
+			continue
+		}
+		if len(byMonthDay) > 0 && !matchMonthDay(d, byMonthDay) {
+			continue
+		}
+		if len(byDay) > 0 && !r.matchDay(d, byDay, byMonth != nil) {
+			continue
+		}
+		set = append(set, d)
+	}
+
+	if len(r.bySetPos) == 0 || len(set) == 0 {
+		return set
+	}
+	var res []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(set) + pos
+		}
+		if i >= 0 && i < len(set) {
+			res = append(res, set[i])
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Before(res[j]) })
+	return res
+}
+
+// matchMonthDay reports whether d is one of the month days, negative values counting from the end.
+func matchMonthDay(d time.Time, days []int) bool {
+	lastDay := lastDayOfMonth(d)
+	for _, day := range days {
+		if day == d.Day() || (day < 0 && lastDay+day+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDay reports whether d matches one of the BYDAY entries. Ordinals are counted
+// within the month for MONTHLY rules and YEARLY rules with BYMONTH, otherwise within the year.
+func (r *rrule) matchDay(d time.Time, days []rruleDay, hasByMonth bool) bool {
+	for _, day := range days {
+		if day.weekday != d.Weekday() {
+			continue
+		}
+		if day.n == 0 {
+			return true
+		}
+		var pos, total int
+		if r.freq == freqMonthly || hasByMonth {
+			pos = (d.Day()-1)/7 + 1
+			total = pos + (lastDayOfMonth(d)-d.Day())/7
+		} else {
+			lastYearDay := time.Date(d.Year(), time.December, 31, 0, 0, 0, 0, d.Location()).YearDay()
+			pos = (d.YearDay()-1)/7 + 1
+			total = pos + (lastYearDay-d.YearDay())/7
+		}
+		if day.n == pos || (day.n < 0 && total+day.n+1 == pos) {
+			return true
+		}
+	}
+	return false
+}
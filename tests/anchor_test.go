@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchor(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   now.Format(`20060102`),
+		"title":  "Полить цветы",
+		"repeat": "d 3",
+		"anchor": "whenever",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// A chore started 10 days ago is due today and then counts from the day it is done
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   now.AddDate(0, 0, -10).Format(`20060102`),
+		"title":  "Полить цветы",
+		"repeat": "d 3",
+		"anchor": "completion",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"])
+	id := fmt.Sprint(ret["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Format(`20060102`), stored.Date)
+	assert.Equal(t, "completion", stored.Anchor)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), stored.Date)
+
+	// Doing it early still counts from the day it is done
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), stored.Date)
+
+	// An update without the anchor keeps it
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   stored.Date,
+		"title":  "Полить все цветы",
+		"repeat": "d 3",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "completion", stored.Anchor)
+
+	// A bill keeps its schedule however early it is paid
+	start := now.AddDate(0, 0, 2).Format(`20060102`)
+	id = addTask(t, task{
+		date:   start,
+		title:  "Оплатить интернет",
+		repeat: "d 3",
+	})
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 5).Format(`20060102`), stored.Date)
+	assert.Equal(t, "schedule", stored.Anchor)
+}
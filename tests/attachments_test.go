@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// doRequest sends the request with the authentication token and returns the response with its body read.
+func doRequest(req *http.Request) (*http.Response, []byte, error) {
+	client := &http.Client{}
+	if len(Token) > 0 {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		jar.SetCookies(req.URL, []*http.Cookie{{Name: "token", Value: Token}})
+		client.Jar = jar
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// upload posts the content as the multipart form field "file" named 'name' to the attachments of the task.
+func upload(t *testing.T, id string, field string, name string, content []byte) (int, map[string]any) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile(field, name)
+	assert.NoError(t, err)
+	_, err = fw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL("api/task/attachments?id="+id), &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, body, err := doRequest(req)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return resp.StatusCode, m
+}
+
+func TestAttachments(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	id := addTask(t, task{date: date, title: "Оплатить счёт за интернет"})
+	attachments := func() []map[string]any {
+		body, err := requestJSON("api/task/attachments?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m["attachments"]
+	}
+
+	content := []byte("Счёт №42 на 1500 рублей")
+	status, ret := upload(t, id, "file", "счёт.txt", content)
+	assert.Equal(t, http.StatusCreated, status, ret)
+	invoice := fmt.Sprint(ret["id"])
+	status, ret = upload(t, id, "file", `..\..\windows\скан.png`, []byte("\x89PNG\r\n\x1a\n"))
+	assert.Equal(t, http.StatusCreated, status, ret)
+	scan := fmt.Sprint(ret["id"])
+
+	list := attachments()
+	assert.Len(t, list, 2)
+	if len(list) == 2 {
+		assert.Equal(t, invoice, list[0]["id"])
+		assert.Equal(t, "счёт.txt", list[0]["name"])
+		assert.Equal(t, float64(len(content)), list[0]["size"])
+		assert.Equal(t, "скан.png", list[1]["name"])
+	}
+
+	req, err := http.NewRequest(http.MethodGet, getURL("api/task/attachment?id="+id+"&attachment="+invoice), nil)
+	assert.NoError(t, err)
+	resp, body, err := doRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, content, body)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+
+	// Uploads are checked on the server
+	status, ret = upload(t, id, "file", "большой.bin", make([]byte, 10<<20+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.NotEmpty(t, ret["error"])
+	status, ret = upload(t, id, "document", "счёт.txt", content)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, ret["error"])
+	status, ret = upload(t, "999999", "file", "счёт.txt", content)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.NotEmpty(t, ret["error"])
+	assert.Len(t, attachments(), 2)
+
+	// Attachments are scoped to their task
+	other := addTask(t, task{date: date, title: "Чужая задача"})
+	ret, err = postJSON("api/task/attachment?id="+other+"&attachment="+invoice, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/task/attachment?id="+id+"&attachment="+scan, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Len(t, attachments(), 1)
+
+	// Purging the task takes its attachments with it
+	for _, task := range []string{id, other} {
+		ret, err = postJSON("api/task?id="+task, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		ret, err = postJSON("api/trash?id="+task, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+	}
+	var count int
+	assert.NoError(t, db.Get(&count, `SELECT count(*) FROM attachments WHERE task_id = ?`, id))
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatchUp(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	// The API moves overdue tasks forward, so the late tasks are written to the database directly
+	addLate := func(title, catchup string) string {
+		res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat, catchup) VALUES (?, ?, '', 'd 2', ?)`,
+			day(-7), title, catchup)
+		assert.NoError(t, err)
+		id, err := res.LastInsertId()
+		assert.NoError(t, err)
+		return fmt.Sprint(id)
+	}
+
+	id := addLate("Проверить почту", "skip")
+	body, err := requestJSON("api/task/missed?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var missed struct {
+		Missed int      `json:"missed"`
+		Dates  []string `json:"dates"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &missed))
+	assert.Equal(t, 4, missed.Missed)
+	assert.Equal(t, []string{day(-7), day(-5), day(-3), day(-1)}, missed.Dates)
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var stored Task
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(1), stored.Date)
+
+	id = addLate("Проверить отчёты", "today")
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(0), stored.Date)
+
+	id = addLate("Проверить счета", "materialize")
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(1), stored.Date)
+	var dates []string
+	assert.NoError(t, db.Select(&dates, `SELECT date FROM scheduler WHERE title = ? AND repeat = '' ORDER BY date`,
+		"Проверить счета"))
+	assert.Equal(t, []string{day(-5), day(-3), day(-1)}, dates)
+	var modes []string
+	assert.NoError(t, db.Select(&modes, `SELECT DISTINCT checklist_mode FROM scheduler WHERE title = ? AND repeat = ''`,
+		"Проверить счета"))
+	assert.Equal(t, []string{"free"}, modes)
+
+	// A limited series uses up the occurrences it catches up on, and today's occurrence is the next one
+	addSeries := func(title, repeat, remaining, catchup string) string {
+		res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat, remaining, catchup) VALUES (?, ?, '', ?, ?, ?)`,
+			day(-4), title, repeat, remaining, catchup)
+		assert.NoError(t, err)
+		id, err := res.LastInsertId()
+		assert.NoError(t, err)
+		return fmt.Sprint(id)
+	}
+	id = addSeries("Выпить витамины", "d 1 count 5", "5", "materialize")
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(0), stored.Date)
+	assert.Equal(t, 1, stored.Remaining)
+	dates = nil
+	assert.NoError(t, db.Select(&dates, `SELECT date FROM scheduler WHERE title = ? AND repeat = '' ORDER BY date`,
+		"Выпить витамины"))
+	assert.Equal(t, []string{day(-3), day(-2), day(-1)}, dates)
+
+	id = addSeries("Полить рассаду", "d 1 count 10", "10", "skip")
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(1), stored.Date)
+	assert.Equal(t, 5, stored.Remaining)
+
+	id = addSeries("Полить рассаду", "d 1 count 5", "5", "skip")
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":    day(0),
+		"title":   "Проверить почту",
+		"repeat":  "d 2",
+		"catchup": "never",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecklist(t *testing.T) {
+	today := time.Now().Format(`20060102`)
+	call := func(path string, values map[string]any, method string) map[string]any {
+		ret, err := postJSON(path, values, method)
+		assert.NoError(t, err)
+		return ret
+	}
+	addItem := func(id, text string) string {
+		ret := call("api/task/checklist?id="+id, map[string]any{"text": text}, http.MethodPost)
+		assert.NotNil(t, ret["id"], text)
+		return fmt.Sprint(ret["id"])
+	}
+	items := func(id string) []map[string]any {
+		body, err := requestJSON("api/task/checklist?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m["items"]
+	}
+	texts := func(id string) []any {
+		var ret []any
+		for _, item := range items(id) {
+			ret = append(ret, item["text"])
+		}
+		return ret
+	}
+	getTask := func(id string) map[string]any {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m
+	}
+
+	assert.NotEmpty(t, call("api/task", map[string]any{"date": today, "title": "Релиз", "checklist_mode": "maybe"},
+		http.MethodPost)["error"])
+
+	// A task that requires its checklist
+	ret := call("api/task", map[string]any{"date": today, "title": "Подготовить релиз", "checklist_mode": "require"},
+		http.MethodPost)
+	release := fmt.Sprint(ret["id"])
+	assert.Equal(t, "require", getTask(release)["checklist_mode"])
+	notes := addItem(release, "Написать заметки")
+	tag := addItem(release, " Поставить тег ")
+	build := addItem(release, "Собрать")
+	assert.NotEmpty(t, call("api/task/checklist?id="+release, map[string]any{"text": " "}, http.MethodPost)["error"])
+	assert.NotEmpty(t, call("api/task/checklist?id=999999", map[string]any{"text": "Нет"}, http.MethodPost)["error"])
+	assert.Equal(t, []any{"Написать заметки", "Поставить тег", "Собрать"}, texts(release))
+
+	assert.Empty(t, call("api/task/checklist/order?id="+release, map[string]any{"items": []string{build, notes, tag}},
+		http.MethodPut))
+	assert.Equal(t, []any{"Собрать", "Написать заметки", "Поставить тег"}, texts(release))
+	for _, order := range [][]string{{build, notes}, {build, notes, notes}, {build, notes, "999999"}} {
+		assert.NotEmpty(t, call("api/task/checklist/order?id="+release, map[string]any{"items": order},
+			http.MethodPut)["error"], order)
+	}
+	assert.Equal(t, []any{"Собрать", "Написать заметки", "Поставить тег"}, texts(release))
+
+	assert.Equal(t, map[string]any{"done": true, "completed": false},
+		call("api/task/checklist/toggle?id="+release+"&item="+build, nil, http.MethodPost))
+	assert.Equal(t, map[string]any{"completed": false}, call("api/task/checklist?id="+release+"&item="+notes,
+		map[string]any{"text": "Написать заметки к релизу", "done": true}, http.MethodPut))
+	assert.NotEmpty(t, call("api/task/checklist/toggle?id="+release+"&item=999999", nil, http.MethodPost)["error"])
+	assert.NotEmpty(t, call("api/task/done?id="+release, nil, http.MethodPost)["error"])
+	assert.Equal(t, map[string]any{"completed": false},
+		call("api/task/checklist?id="+release+"&item="+tag, nil, http.MethodDelete))
+	assert.Equal(t, []any{"Собрать", "Написать заметки к релизу"}, texts(release))
+	assert.Empty(t, call("api/task/done?id="+release, nil, http.MethodPost))
+	assert.NotEmpty(t, getTask(release)["error"])
+
+	// A repeating task that completes itself and starts every occurrence with a clean checklist
+	ret = call("api/task", map[string]any{"date": today, "title": "Утренний обход", "repeat": "d 1", "checklist_mode": "auto"},
+		http.MethodPost)
+	round := fmt.Sprint(ret["id"])
+	doors := addItem(round, "Двери")
+	windows := addItem(round, "Окна")
+	assert.Equal(t, map[string]any{"done": true, "completed": false},
+		call("api/task/checklist/toggle?id="+round+"&item="+doors, nil, http.MethodPost))
+	assert.Equal(t, map[string]any{"done": true, "completed": true},
+		call("api/task/checklist/toggle?id="+round+"&item="+windows, nil, http.MethodPost))
+	next := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	assert.Equal(t, next, getTask(round)["date"])
+	for _, item := range items(round) {
+		assert.Equal(t, false, item["done"], item["text"])
+	}
+
+	// Purging the task takes its checklist with it
+	assert.Empty(t, call("api/task?id="+round, nil, http.MethodDelete))
+	assert.Empty(t, call("api/trash?id="+round, nil, http.MethodDelete)["error"])
+	db := openDB(t)
+	defer db.Close()
+	var count int
+	assert.NoError(t, db.Get(&count, `SELECT count(*) FROM checklist WHERE task_id IN (?, ?)`, release, round))
+	assert.Equal(t, 0, count)
+}
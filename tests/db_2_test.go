@@ -11,11 +11,21 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID            int64  `db:"id"`
+	Date          string `db:"date"`
+	Title         string `db:"title"`
+	Comment       string `db:"comment"`
+	Repeat        string `db:"repeat"`
+	Remaining     int    `db:"remaining"`
+	EndDate       string `db:"end_date"`
+	Time          string `db:"time"`
+	TimeZone      string `db:"timezone"`
+	Anchor        string `db:"anchor"`
+	CatchUp       string `db:"catchup"`
+	Priority      int    `db:"priority"`
+	ProjectID     int64  `db:"project_id"`
+	ChecklistMode string `db:"checklist_mode"`
+	DeletedAt     string `db:"deleted_at"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepends(t *testing.T) {
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	getTask := func(id string) map[string]any {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m
+	}
+	add := func(values map[string]any) (string, any) {
+		ret, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		if ret["error"] != nil {
+			return "", ret["error"]
+		}
+		return fmt.Sprint(ret["id"]), nil
+	}
+
+	order, _ := add(map[string]any{"date": day(1), "title": "Заказать детали"})
+	install, msg := add(map[string]any{"title": "Установить детали", "depends": "after " + order + " +3d"})
+	assert.Nil(t, msg)
+	verify, msg := add(map[string]any{"title": "Проверить установку", "depends": "after " + install + " +1w"})
+	assert.Nil(t, msg)
+
+	m := getTask(install)
+	assert.Equal(t, day(4), m["date"])
+	assert.Equal(t, "after "+order+" +3d", m["depends"])
+	m = getTask(verify)
+	assert.Equal(t, day(11), m["date"])
+	assert.Equal(t, "after "+install+" +7d", m["depends"])
+
+	for _, spec := range []string{"after", "after x", "after " + order + " +3m", "after " + order + " 3d", "after 999999"} {
+		_, msg := add(map[string]any{"date": day(1), "title": "Зависимая задача", "depends": spec})
+		assert.NotNil(t, msg, spec)
+	}
+
+	// The first step cannot wait for the last one
+	ret, err := postJSON("api/task", map[string]any{
+		"id":      order,
+		"date":    day(1),
+		"title":   "Заказать детали",
+		"depends": "after " + verify,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Contains(t, ret["error"], "cycle")
+
+	// Completing the first step moves the rest of the chain
+	ret, err = postJSON("api/task/done?id="+order, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	m = getTask(install)
+	assert.Equal(t, day(3), m["date"])
+	assert.Equal(t, "", m["depends"])
+	m = getTask(verify)
+	assert.Equal(t, day(10), m["date"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":      verify,
+		"date":    day(10),
+		"title":   "Проверить установку",
+		"depends": "none",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, "", getTask(verify)["depends"])
+
+	for _, id := range []string{install, verify} {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
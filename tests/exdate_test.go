@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExdates(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Утренняя пробежка",
+		repeat: "d 1",
+	})
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+
+	ret, err := postJSON("api/task/exdate?id="+id+"&date="+tomorrow, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/exdate?id="+id+"&date=2024", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, []any{tomorrow}, m["exdates"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), stored.Date)
+
+	// Excluding the current date moves the task on
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+stored.Date, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), stored.Date)
+
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+tomorrow, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+tomorrow, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	var left int
+	err = db.Get(&left, `SELECT count(*) FROM exdates WHERE task_id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, left)
+}
+
+func TestExdatesLimited(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	id := addTask(t, task{
+		date:   day(1),
+		title:  "Сеанс массажа",
+		repeat: "d 1 count 4",
+	})
+	dates := func() []any {
+		body, err := requestJSON("api/tasks?from="+day(0)+"&to="+day(9), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var res []any
+		for _, inst := range m["tasks"] {
+			if inst["id"] == id {
+				res = append(res, inst["date"])
+			}
+		}
+		return res
+	}
+
+	// Excluded dates count toward the limit whether they are ahead or current
+	ret, err := postJSON("api/task/exdate?id="+id+"&date="+day(3), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []any{day(1), day(2), day(4)}, dates())
+
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+day(1), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []any{day(2), day(4)}, dates())
+	var stored Task
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(2), stored.Date)
+	assert.Equal(t, 3, stored.Remaining)
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, 3.0, m["remaining"])
+
+	// Excluding the current date moves past the excluded one as well
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+day(2), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(4), stored.Date)
+	assert.Equal(t, 1, stored.Remaining)
+
+	ret, err = postJSON("api/task/exdate?id="+id+"&date="+day(4), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+	today := now.Format(`20060102`)
+	call := func(path string, values map[string]any, method string) map[string]any {
+		ret, err := postJSON(path, values, method)
+		assert.NoError(t, err)
+		return ret
+	}
+	history := func(query string) []map[string]any {
+		body, err := requestJSON("api/history?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m["history"]
+	}
+
+	ret := call("api/task", map[string]any{"date": today, "title": "Оплатить счёт"}, http.MethodPost)
+	bill := fmt.Sprint(ret["id"])
+	ret = call("api/task", map[string]any{"date": today, "title": "Полить цветы", "repeat": "d 3"}, http.MethodPost)
+	plants := fmt.Sprint(ret["id"])
+
+	assert.Empty(t, call("api/task/done?id="+bill, nil, http.MethodPost))
+	h := history("id=" + bill)
+	assert.Len(t, h, 1)
+	if len(h) == 1 {
+		assert.Equal(t, bill, h[0]["task_id"])
+		assert.Equal(t, "Оплатить счёт", h[0]["title"])
+		assert.Equal(t, today, h[0]["date"])
+		at, err := time.Parse(time.RFC3339, fmt.Sprint(h[0]["completed_at"]))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, now, at, time.Minute)
+	}
+
+	// The title is a snapshot, and later occurrences are recorded on their own date
+	assert.Empty(t, call("api/task/done?id="+plants, nil, http.MethodPost))
+	assert.Empty(t, call("api/task", map[string]any{"id": plants, "date": now.AddDate(0, 0, 3).Format(`20060102`),
+		"title": "Полить все цветы", "repeat": "d 3"}, http.MethodPut))
+	later := now.AddDate(0, 0, 6).Format(`20060102`)
+	assert.Empty(t, call("api/task/done?id="+plants+"&date="+later, nil, http.MethodPost))
+	h = history("id=" + plants)
+	assert.Len(t, h, 2)
+	if len(h) == 2 {
+		assert.Equal(t, "Полить все цветы", h[0]["title"])
+		assert.Equal(t, later, h[0]["date"])
+		assert.Equal(t, "Полить цветы", h[1]["title"])
+		assert.Equal(t, today, h[1]["date"])
+	}
+
+	// The range is of the days the tasks were done on
+	assert.Len(t, history("id="+plants+"&from="+today+"&to="+today), 2)
+	assert.Len(t, history("id="+plants+"&to="+today), 2)
+	assert.Empty(t, history("id="+plants+"&from="+now.AddDate(0, 0, 1).Format(`20060102`)))
+	assert.Empty(t, history("id="+plants+"&to="+now.AddDate(0, 0, -1).Format(`20060102`)))
+	for _, query := range []string{"from=2024", "to=20241301", "from=20240201&to=20240101"} {
+		body, err := requestJSON("api/history?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.NotEmpty(t, m["error"], query)
+	}
+
+	assert.Empty(t, call("api/task?id="+plants, nil, http.MethodDelete))
+}
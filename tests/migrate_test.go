@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/somepgs/go_final_project/pkg/db"
+)
+
+// baselineSchema is the schema of the first release, which had no migrations.
+const baselineSchema = `
+CREATE TABLE scheduler (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	date CHAR(8) NOT NULL DEFAULT "",
+	title VARCHAR(128) NOT NULL DEFAULT "",
+	comment TEXT NOT NULL DEFAULT "",
+	repeat VARCHAR(128) NOT NULL DEFAULT ""
+	);
+CREATE INDEX idx_scheduler_date ON scheduler (date);`
+
+// createDB creates a database file with the given statements and one task in it.
+func createDB(t *testing.T, schema string) string {
+	file := filepath.Join(t.TempDir(), "scheduler.db")
+	raw, err := sqlx.Connect("sqlite", file)
+	assert.NoError(t, err)
+	defer raw.Close()
+	_, err = raw.Exec(schema)
+	assert.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Оплатить счета', '', 'm 25')`)
+	assert.NoError(t, err)
+	return file
+}
+
+func columnCount(t *testing.T, file string) int {
+	raw, err := sqlx.Connect("sqlite", file)
+	assert.NoError(t, err)
+	defer raw.Close()
+	var n int
+	assert.NoError(t, raw.Get(&n, `SELECT count(*) FROM pragma_table_info('scheduler')`))
+	return n
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	file := createDB(t, baselineSchema)
+	assert.NoError(t, db.Init(file))
+	defer db.Close()
+
+	version, err := db.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, db.LatestVersion(), version)
+
+	raw, err := sqlx.Connect("sqlite", file)
+	assert.NoError(t, err)
+	defer raw.Close()
+	var task Task
+	assert.NoError(t, raw.Get(&task, `SELECT * FROM scheduler`))
+	assert.Equal(t, "Оплатить счета", task.Title)
+	assert.Equal(t, "schedule", task.Anchor)
+	assert.Equal(t, "skip", task.CatchUp)
+	var tables int
+	assert.NoError(t, raw.Get(&tables, `SELECT count(*) FROM sqlite_master
+		WHERE type = 'table' AND name IN ('exdates', 'overrides', 'dependencies')`))
+	assert.Equal(t, 3, tables)
+
+	// Down to the first release and back up, keeping the task
+	assert.NoError(t, db.Migrate(1))
+	assert.Equal(t, 5, columnCount(t, file))
+	assert.NoError(t, raw.Get(&tables, `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'exdates'`))
+	assert.Equal(t, 0, tables)
+	status, err := db.Migrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, status[0].AppliedAt)
+	assert.Empty(t, status[1].AppliedAt)
+
+	assert.NoError(t, db.Migrate(db.LatestVersion()))
+	assert.NoError(t, raw.Get(&task, `SELECT * FROM scheduler`))
+	assert.Equal(t, "m 25", task.Repeat)
+
+	assert.Error(t, db.Migrate(db.LatestVersion()+1))
+	assert.Error(t, db.Migrate(-1))
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	// A database the columns were added to one by one before there were migrations
+	file := createDB(t, baselineSchema+`
+		ALTER TABLE scheduler ADD COLUMN remaining INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE scheduler ADD COLUMN end_date CHAR(8) NOT NULL DEFAULT "";
+		ALTER TABLE scheduler ADD COLUMN time CHAR(5) NOT NULL DEFAULT "";
+		CREATE TABLE exdates (task_id INTEGER NOT NULL, date CHAR(8) NOT NULL, PRIMARY KEY (task_id, date));`)
+	assert.NoError(t, db.Init(file))
+	version, err := db.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, db.LatestVersion(), version)
+	assert.NoError(t, db.Close())
+
+	// Starting again changes nothing, and the table ends up as in a new database
+	assert.NoError(t, db.Init(file))
+	assert.NoError(t, db.Close())
+	fresh := filepath.Join(t.TempDir(), "fresh.db")
+	assert.NoError(t, db.Init(fresh))
+	assert.NoError(t, db.Close())
+	assert.Equal(t, columnCount(t, fresh), columnCount(t, file))
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkNextDate(t *testing.T, now string, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=%s&date=%s&repeat=%s",
+			now, url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestNextDateWeekdayOfMonth(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "mw", ""},
+		{"20240101", "mw 0:1", ""},
+		{"20240101", "mw 6:1", ""},
+		{"20240101", "mw 2:8", ""},
+		{"20240101", "mw 2", ""},
+		{"20240101", "mw 1:1 13", ""},
+		{"20240101", "mw 2:1", "20240212"},
+		{"20240101", "mw -1:5", "20240223"},
+		{"20240101", "mw 1:1,-1:5 3", "20240304"},
+		{"20240101", "mw 5:1 2", "20440229"},
+		{"20240101", "mw -5:1 2", "20440201"},
+		{"20240301", "mw 1:2", "20240305"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "RRULE:", ""},
+		{"20240101", "RRULE:COUNT=2", ""},
+		{"20240101", "RRULE:FREQ=HOURLY", ""},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=2MO", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3;UNTIL=20240301", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240120", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240201", "20240127"},
+		{"20240113", "RRULE:FREQ=DAILY;INTERVAL=7", "20240127"},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=MO,TH", "20240129"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=-1", "20240331"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYDAY=20MO", "20240513"},
+		{"20240229", "RRULE:FREQ=YEARLY", "20280229"},
+	}
+	checkNextDate(t, "20240126", tbl)
+
+	tbl = []nextDate{
+		{"19970805", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU", "19970817"},
+		{"19970805", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=MO", "19970819"},
+	}
+	checkNextDate(t, "19970811", tbl)
+}
+
+func TestNextDateCron(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "cron", ""},
+		{"20240101", "cron * * * *", ""},
+		{"20240101", "cron 60 * * * *", ""},
+		{"20240101", "cron * * 32 * *", ""},
+		{"20240101", "cron * * * 13 *", ""},
+		{"20240101", "cron * * * * 8", ""},
+		{"20240101", "cron * * 30 2 *", ""},
+		{"20240101", "cron * * * * *", "20240127"},
+		{"20240101", "cron 0 9 * * 1-5", "20240129"},
+		{"20240101", "cron 30 18 1,15 * *", "20240201"},
+		{"20240101", "cron 0 0 */10 * *", "20240131"},
+		{"20240101", "cron 0 0 1 */3 *", "20240401"},
+		{"20240101", "cron 0 0 * MAR SUN", "20240303"},
+		{"20240101", "cron 0 0 13 * 5", "20240202"},
+		{"20240101", "cron 0 0 L * *", "20240131"},
+		{"20240101", "cron 0 0 LW 3 *", "20240329"},
+		{"20240101", "cron 0 0 1W 6 *", "20240603"},
+		{"20240101", "cron 0 0 ? * 5L", "20240223"},
+		{"20240101", "cron 0 0 ? * 1#2", "20240212"},
+		{"20240101", "cron 0 0 29 2 *", "20240229"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
+
+func TestNextDateEnd(t *testing.T) {
+	tbl := []nextDate{
+		{"20240113", "d 7 until 2024", ""},
+		{"20240113", "d 7 count 0", ""},
+		{"20240113", "d 7 count 3 count 4", ""},
+		{"20240113", "d 7 count 10001", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=10001", ""},
+		{"20240113", "d 7 until 20240126", ""},
+		{"20240113", "d 7 until 20240127", "20240127"},
+		{"20240113", "d 7 count 10", "20240127"},
+		{"20240113", "d 7 count 3", "20240127"},
+		{"20240113", "d 7 count 2", ""},
+		{"20240101", "w 1,4 until 20271231 count 9", "20240129"},
+		{"20240101", "w 1,4 until 20271231 count 8", ""},
+		{"20240101", "m 1,15 count 3", "20240201"},
+		{"20240101", "m 1,15 count 2", ""},
+		{"20230101", "y count 3", "20250101"},
+		{"20230101", "y count 2", ""},
+		{"20240101", "y until 20250101", "20250101"},
+		{"20240101", "y until 20241231", ""},
+		{"20240101", "mw 2:1 until 20240301", "20240212"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
+
+func TestNextDateWeekNumberQuarter(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "wn", ""},
+		{"20240101", "wn 0", ""},
+		{"20240101", "wn 54", ""},
+		{"20240101", "wn 1 8", ""},
+		{"20240101", "wn 1 1 1", ""},
+		{"20240101", "q", ""},
+		{"20240101", "q 0", ""},
+		{"20240101", "q 93", ""},
+		{"20240101", "q -93", ""},
+		{"20240101", "q 1 2", ""},
+		{"20240101", "wn 1,14,27,40", "20240401"},
+		{"20240101", "wn 5 5", "20240202"},
+		{"20240101", "wn 53 5", "20270101"},
+		{"20240101", "q 1", "20240401"},
+		{"20240101", "q 45", "20240214"},
+		{"20240101", "q -1", "20240331"},
+		{"20240101", "q 92", "20240930"},
+		{"20240101", "q -1 shift prev", "20240329"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
+
+func TestNextDateExdates(t *testing.T) {
+	get, err := getBody("api/nextdate?now=20240126&date=20240113&repeat=d+7&exdates=20240127,20240203")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240210", strings.TrimSpace(string(get)))
+
+	// Excluded dates still count toward the count modifier
+	get, err = getBody("api/nextdate?now=20240126&date=20240113&repeat=d+7+count+4&exdates=20240127")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240203", strings.TrimSpace(string(get)))
+
+	get, err = getBody("api/nextdate?now=20240126&date=20240113&repeat=d+7+count+3&exdates=20240127")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "20240203", strings.TrimSpace(string(get)))
+}
+
+func TestNextDateWorkdays(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "bd", ""},
+		{"20240101", "bd 0", ""},
+		{"20240101", "bd 400", ""},
+		{"20240101", "m 17 shift up", ""},
+		{"20240101", "m 17 shift next shift prev", ""},
+		{"20240101", "RRULE:FREQ=DAILY count 3", ""},
+		{"20240101", "bd 5", "20240129"},
+		{"20240101", "m 13 shift next", "20240213"},
+		{"20240101", "m 17 shift next", "20240219"},
+		{"20240101", "m 17 shift prev", "20240216"},
+		{"20240101", "m 28 shift prev", "20240228"},
+		{"20240113", "d 7 shift next", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=17 shift next", "20240219"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
+
+func TestNextDateInterval(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "y 0", ""},
+		{"20240101", "y 101", ""},
+		{"20240101", "y x", ""},
+		{"20240101", "y 2 3", ""},
+		{"20240101", "y 1", "20250101"},
+		{"20200315", "y 3", "20260315"},
+		{"20240301", "y 2", "20260301"},
+		{"20240101", "w 1 2", ""},
+		{"20240101", "w 1 /0", ""},
+		{"20240101", "w 1 /53", ""},
+		{"20240101", "w 1 /2 /3", ""},
+		{"20240101", "w 1 /1", "20240129"},
+		{"20240101", "w 1,4 /2", "20240129"},
+		{"20240108", "w 1,4 /2", "20240205"},
+		{"20240103", "w 7 /3", "20240128"},
+		{"20240125", "w 4 /4", "20240222"},
+	}
+	checkNextDate(t, "20240126", tbl)
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrenceOverrides(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	id := addTask(t, task{
+		date:    day(1),
+		title:   "Полить цветы",
+		comment: "на балконе",
+		repeat:  "d 2",
+	})
+	// instances returns the listed occurrences of the task, the ones between today and nine days later for a range
+	instances := func(query string) []map[string]any {
+		body, err := requestJSON("api/tasks"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var res []map[string]any
+		for _, inst := range m["tasks"] {
+			if inst["id"] == id {
+				res = append(res, inst)
+			}
+		}
+		return res
+	}
+	week := "?from=" + day(0) + "&to=" + day(9)
+	dates := func(list []map[string]any) []any {
+		var res []any
+		for _, inst := range list {
+			res = append(res, inst["date"])
+		}
+		return res
+	}
+	assert.Equal(t, []any{day(1), day(3), day(5), day(7), day(9)}, dates(instances(week)))
+
+	// Move one occurrence and give it its own comment
+	ret, err := postJSON("api/task/occurrence?id="+id+"&date="+day(3), map[string]any{
+		"date":    day(4),
+		"comment": "на кухне",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	list := instances(week)
+	assert.Equal(t, []any{day(1), day(4), day(5), day(7), day(9)}, dates(list))
+	assert.Equal(t, day(3), list[1]["occurrence"])
+	assert.Equal(t, "на кухне", list[1]["comment"])
+	assert.Equal(t, "на балконе", list[2]["comment"])
+
+	body, err := requestJSON("api/task/occurrence?id="+id+"&date="+day(3), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var inst map[string]string
+	assert.NoError(t, json.Unmarshal(body, &inst))
+	assert.Equal(t, day(4), inst["date"])
+	assert.Equal(t, "на кухне", inst["comment"])
+
+	ret, err = postJSON("api/task/occurrence?id="+id+"&date="+day(2), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Skip one occurrence and complete another one ahead of time
+	ret, err = postJSON("api/task/occurrence?id="+id+"&date="+day(5), nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/done?id="+id+"&date="+day(7), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []any{day(1), day(4), day(9)}, dates(instances(week)))
+
+	// The series itself is unchanged
+	var stored Task
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(1), stored.Date)
+	assert.Equal(t, "на балконе", stored.Comment)
+
+	// Once the series reaches the moved occurrence, the task list shows it as moved
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	list = instances("")
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, day(4), list[0]["date"])
+		assert.Equal(t, day(3), list[0]["occurrence"])
+	}
+
+	// Moving past an overridden occurrence drops its override
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, day(9), stored.Date)
+	var overrides int
+	assert.NoError(t, db.Get(&overrides, `SELECT count(*) FROM overrides WHERE task_id=?`, id))
+	assert.Equal(t, 0, overrides)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestDoneOccurrenceLimited(t *testing.T) {
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	call := func(path string, values map[string]any, method string) map[string]any {
+		ret, err := postJSON(path, values, method)
+		assert.NoError(t, err)
+		return ret
+	}
+	// The tag keeps the listing to the tasks of this test
+	dates := func(id string) []any {
+		body, err := requestJSON("api/tasks?from="+day(0)+"&to="+day(9)+"&tag=уколы-и-склад", nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var res []any
+		for _, inst := range m["tasks"] {
+			if inst["id"] == id {
+				res = append(res, inst["date"])
+			}
+		}
+		return res
+	}
+
+	// A later occurrence done ahead of time is one of the limited series, which does not grow a new one
+	ret := call("api/task", map[string]any{"date": day(1), "title": "Курс уколов", "repeat": "d 2 count 3",
+		"tags": []string{"уколы-и-склад"}}, http.MethodPost)
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, []any{day(1), day(3), day(5)}, dates(id))
+	assert.Empty(t, call("api/task/done?id="+id+"&date="+day(3), nil, http.MethodPost))
+	assert.Equal(t, []any{day(1), day(5)}, dates(id))
+
+	// An occurrence of a task that requires its checklist is not done while items are open
+	ret = call("api/task", map[string]any{"date": day(1), "title": "Обход склада", "repeat": "d 2",
+		"checklist_mode": "require", "tags": []string{"уколы-и-склад"}}, http.MethodPost)
+	other := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, call("api/task/checklist?id="+other, map[string]any{"text": "Ворота"}, http.MethodPost)["id"])
+	assert.NotEmpty(t, call("api/task/done?id="+other+"&date="+day(3), nil, http.MethodPost)["error"])
+	assert.Equal(t, []any{day(1), day(3), day(5), day(7), day(9)}, dates(other))
+
+	for _, task := range []string{id, other} {
+		assert.Empty(t, call("api/task?id="+task, nil, http.MethodDelete))
+	}
+}
+
+func TestRangeTruncated(t *testing.T) {
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	ret, err := postJSON("api/task", map[string]any{"date": day(0), "title": "Полить рассаду", "repeat": "d 1",
+		"tags": []string{"рассада"}}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	// A listing cut short says so
+	list := func(to int) ([]any, bool) {
+		body, err := requestJSON("api/tasks?from="+day(0)+"&to="+day(to)+"&tag=рассада", nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		tasks, _ := m["tasks"].([]any)
+		truncated, _ := m["truncated"].(bool)
+		return tasks, truncated
+	}
+	tasks, truncated := list(9)
+	assert.Len(t, tasks, 10)
+	assert.False(t, truncated)
+	tasks, truncated = list(59)
+	assert.Len(t, tasks, 50)
+	assert.True(t, truncated)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		query string
+		want  []string
+	}{
+		{"date=20240113&repeat=d+7&count=3", []string{"20240127", "20240203", "20240210"}},
+		{"date=20240101&repeat=mw+-1:5&count=2", []string{"20240223", "20240329"}},
+		{"date=20240113&repeat=d+7&count=10&until=20240205", []string{"20240127", "20240203"}},
+		{"date=20240101&repeat=d+7+count+5&count=10", []string{"20240129"}},
+		{"date=20240125&repeat=RRULE:FREQ%3DDAILY%3BCOUNT%3D4&count=5", []string{"20240127", "20240128"}},
+		{"date=20240101&repeat=RRULE:FREQ%3DDAILY%3BCOUNT%3D3", nil},
+		{"date=20240113&repeat=d+7&count=101", nil},
+		{"date=20240113&repeat=d+7&count=0", nil},
+		{"date=20240113&repeat=k+7", nil},
+		{"date=20240113&repeat=d+7&until=2024", nil},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/occurrences?now=20240126&" + v.query)
+		assert.NoError(t, err)
+		var m map[string]any
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		if v.want == nil {
+			assert.NotEmpty(t, m["error"], v.query)
+			continue
+		}
+		dates, _ := m["dates"].([]any)
+		var got []string
+		for _, d := range dates {
+			got = append(got, d.(string))
+		}
+		assert.Equal(t, v.want, got, v.query)
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatParse(t *testing.T) {
+	tbl := []struct {
+		text string
+		want string
+	}{
+		{"every 2 weeks on Monday and Friday", "w 1,5 /2"},
+		{"каждый последний день месяца", "m -1"},
+		{"first and last day of February and August", "m 1,-1 2,8"},
+		{"last friday of the month", "mw -1:5"},
+		{"во вторую среду каждого месяца", "mw 2:3"},
+		{"каждые 3 дня", "d 3"},
+		{"every other day", "d 2"},
+		{"15-го числа каждого месяца", "m 15"},
+		{"по будням", "w 1,2,3,4,5"},
+		{"every 3 working days", "bd 3"},
+		{"каждые 2 часа", "h 2"},
+		{"every year", "y"},
+		{"every 2 years", "y 2"},
+		{"every fortnight", "d 14"},
+		{"every fortnight on Monday", "w 1 /2"},
+		{"every 3rd day", "d 3"},
+		{"каждый 3-й день", "d 3"},
+		{"15th day of the month", "m 15"},
+		{"каждый месяц", ""},
+		{"when pigs fly", ""},
+		{"every day at 9", ""},
+		{"twice a week", ""},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/repeat/parse?text=" + url.QueryEscape(v.text))
+		assert.NoError(t, err)
+		var m map[string]any
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		if v.want == "" {
+			assert.NotEmpty(t, m["error"], v.text)
+			continue
+		}
+		assert.Equal(t, v.want, m["repeat"], v.text)
+	}
+}
+
+func TestRepeatDescribe(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		lang   string
+		want   string
+	}{
+		{"d 1", "en", "Every day"},
+		{"d 21", "ru", "Каждый 21 день"},
+		{"w 1,5 /2", "en", "Every 2 weeks on Monday and Friday"},
+		{"w 1,5 /2", "ru", "Каждые 2 недели по понедельникам и пятницам"},
+		{"m 1,-1 2,8", "en", "On the 1st and last day of February and August"},
+		{"m 1,-1 2,8", "", "1-го и последнего числа февраля и августа"},
+		{"mw -1:5", "ru", "В последнюю пятницу каждого месяца"},
+		{"wn 1,14,27,40", "en", "On Monday of ISO weeks 1, 14, 27 and 40"},
+		{"q 1,-1", "ru", "1-го и последнего дня каждого квартала"},
+		{"d 7 until 20240126", "en", "Every 7 days, until January 26, 2024"},
+		{"w 1 count 3", "ru", "Каждую неделю по понедельникам, 3 раза"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=10", "en", "On the last Friday of every month, 10 times"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "ru", "Каждые 2 недели по понедельникам и пятницам"},
+		{"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", "ru", "Во второе воскресенье марта"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=MO,FR;BYSETPOS=-1;COUNT=3", "en", "By the rule RRULE:FREQ=MONTHLY;BYDAY=MO,FR;BYSETPOS=-1;COUNT=3"},
+		{"cron 30 18 * * 1-5", "en", "Every week on Monday, Tuesday, Wednesday, Thursday and Friday at 18:30"},
+		{"cron 0 9 1,15 * *", "ru", "1-го и 15-го числа каждого месяца в 09:00"},
+		{"cron */15 9-17 * * *", "en", "On the cron schedule */15 9-17 * * *"},
+		{"k 7", "en", ""},
+		{"d 7", "fr", ""},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/repeat/describe?repeat=" + url.QueryEscape(v.repeat) + "&lang=" + v.lang)
+		assert.NoError(t, err)
+		var m map[string]any
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		if v.want == "" {
+			assert.NotEmpty(t, m["error"], v.repeat)
+			continue
+		}
+		assert.Equal(t, v.want, m["text"], v.repeat)
+	}
+}
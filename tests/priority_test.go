@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 2)
+	// listTasks returns the IDs and priorities of the listed tasks
+	listTasks := func(query string) ([]string, []string) {
+		body, err := requestJSON("api/tasks"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var ids, priorities []string
+		for _, task := range m["tasks"] {
+			ids = append(ids, fmt.Sprint(task["id"]))
+			priorities = append(priorities, fmt.Sprint(task["priority"]))
+		}
+		return ids, priorities
+	}
+
+	var ids []string
+	for _, v := range []struct {
+		title    string
+		priority string
+	}{
+		{"Купить хлеб", ""},
+		{"Сдать отчёт", "1"},
+		{"Позвонить маме", "2"},
+	} {
+		values := map[string]any{"date": date.Format(`20060102`), "title": v.title}
+		if v.priority != "" {
+			values["priority"] = v.priority
+		}
+		ret, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+	// A plain number and a label are read as well
+	for _, p := range []any{3, "P3", "p3"} {
+		ret, err := postJSON("api/task", map[string]any{"date": date.Format(`20060102`), "title": "Полить цветы",
+			"priority": p}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"], p)
+		body, err := requestJSON(fmt.Sprint("api/task?id=", ret["id"]), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]any
+		assert.NoError(t, json.Unmarshal(body, &task))
+		assert.Equal(t, "3", task["priority"], p)
+		ret, err = postJSON(fmt.Sprint("api/task?id=", ret["id"]), nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	for _, p := range []any{"0x", "5", "-1", 5, "P5", "PP1", true} {
+		ret, err := postJSON("api/task", map[string]any{"title": "Купить хлеб", "priority": p}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], p)
+	}
+
+	// The urgent task comes first and the one without a priority last
+	listed, priorities := listTasks("?search=" + date.Format(`02.01.2006`))
+	var order []string
+	for i, id := range listed {
+		if id == ids[0] || id == ids[1] || id == ids[2] {
+			order = append(order, id+":"+priorities[i])
+		}
+	}
+	assert.Equal(t, []string{ids[1] + ":1", ids[2] + ":2", ids[0] + ":4"}, order)
+
+	listed, priorities = listTasks("?priority=P1")
+	assert.Contains(t, listed, ids[1])
+	assert.NotContains(t, listed, ids[0])
+	for _, p := range priorities {
+		assert.Equal(t, "1", p)
+	}
+	body, err := requestJSON("api/tasks?priority=9", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	// An update without a priority keeps the stored one
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    ids[1],
+		"date":  date.Format(`20060102`),
+		"title": "Сдать годовой отчёт",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	body, err = requestJSON("api/task?id="+ids[1], nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "1", task["priority"])
+
+	for _, id := range ids {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
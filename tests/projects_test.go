@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjects(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	call := func(path string, values map[string]any, method string) map[string]any {
+		ret, err := postJSON(path, values, method)
+		assert.NoError(t, err)
+		return ret
+	}
+	addProject := func(values map[string]any) string {
+		ret := call("api/projects", values, http.MethodPost)
+		assert.NotNil(t, ret["id"], values)
+		return fmt.Sprint(ret["id"])
+	}
+	projects := func(query string) []map[string]any {
+		body, err := requestJSON("api/projects"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m["projects"]
+	}
+	listed := func(query string) []string {
+		body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var ids []string
+		for _, task := range m["tasks"] {
+			ids = append(ids, fmt.Sprint(task["id"]))
+		}
+		return ids
+	}
+	projectOf := func(id string) any {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m["project_id"]
+	}
+
+	for _, p := range []map[string]any{{"name": " "}, {"name": "Дом", "color": "red"}, {"name": "Дом", "color": "#12345"}} {
+		assert.NotEmpty(t, call("api/projects", p, http.MethodPost)["error"], p)
+	}
+	home := addProject(map[string]any{"name": " Дом ", "color": "#00AA00"})
+	work := addProject(map[string]any{"name": "Работа"})
+
+	body, err := requestJSON("api/projects/"+home, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var p map[string]any
+	assert.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, "Дом", p["name"])
+	assert.Equal(t, "#00aa00", p["color"])
+	assert.Equal(t, false, p["archived"])
+	assert.NotEmpty(t, call("api/projects/0", nil, http.MethodGet)["error"])
+
+	ret := call("api/task", map[string]any{"date": date, "title": "Починить кран", "project_id": home}, http.MethodPost)
+	kitchen := fmt.Sprint(ret["id"])
+	ret = call("api/task", map[string]any{"date": date, "title": "Написать отчёт", "project_id": work}, http.MethodPost)
+	report := fmt.Sprint(ret["id"])
+	ret = call("api/task", map[string]any{"date": date, "title": "Без проекта"}, http.MethodPost)
+	loose := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, call("api/task", map[string]any{"date": date, "title": "Нет проекта", "project_id": "999999"},
+		http.MethodPost)["error"])
+	assert.Equal(t, home, projectOf(kitchen))
+	assert.Equal(t, "", projectOf(loose))
+
+	ids := listed("project=" + home)
+	assert.Equal(t, []string{kitchen}, ids)
+	ids = listed("project=0")
+	assert.Contains(t, ids, loose)
+	assert.NotContains(t, ids, kitchen)
+	assert.Equal(t, []string{report}, listed("project="+work+"&search=отчёт"))
+	assert.Empty(t, listed("project="+home+"&search=отчёт"))
+
+	// An update keeps the project unless it is given, "0" clears it
+	assert.Empty(t, call("api/task", map[string]any{"id": kitchen, "date": date, "title": "Починить кран"}, http.MethodPut))
+	assert.Equal(t, home, projectOf(kitchen))
+	assert.Empty(t, call("api/task", map[string]any{"id": loose, "date": date, "title": "Без проекта", "project_id": "0"},
+		http.MethodPut))
+	assert.Equal(t, "", projectOf(loose))
+
+	// Archived projects are listed on request and take no new tasks
+	assert.Empty(t, call("api/projects/"+work, map[string]any{"name": "Работа", "archived": true, "position": 5},
+		http.MethodPut))
+	for _, p := range projects("") {
+		assert.NotEqual(t, work, p["id"])
+	}
+	var found bool
+	for _, p := range projects("?archived=1") {
+		found = found || p["id"] == work
+	}
+	assert.True(t, found)
+	assert.NotEmpty(t, call("api/task", map[string]any{"id": loose, "date": date, "title": "Без проекта", "project_id": work},
+		http.MethodPut)["error"])
+	assert.Empty(t, call("api/task", map[string]any{"id": report, "date": date, "title": "Написать отчёт (черновик)"},
+		http.MethodPut))
+
+	// Deleting a project keeps its tasks
+	assert.Empty(t, call("api/projects/"+home, nil, http.MethodDelete))
+	assert.Equal(t, "", projectOf(kitchen))
+	assert.NotEmpty(t, call("api/projects/"+home, nil, http.MethodDelete)["error"])
+	assert.Empty(t, call("api/projects/"+work, nil, http.MethodDelete))
+
+	for _, id := range []string{kitchen, report, loose} {
+		assert.Empty(t, call("api/task?id="+id, nil, http.MethodDelete))
+	}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+// BenchmarkRecurNext measures Rule.Next for tasks started 1, 10 and 100 years before now.
+// The time per operation should stay the same for every gap, except for rules with a count: they walk
+// their occurrences from the start, at most as many as the count, and may end before now.
+func BenchmarkRecurNext(b *testing.B) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+	rules := []string{
+		"d 1",
+		"bd 1",
+		"y",
+		"w 1,5 /2",
+		"m 1,-1",
+		"mw -1:5",
+		"wn 1,14,27,40",
+		"q 1,-1",
+		"cron 0 9 * * 1-5",
+		"RRULE:FREQ=DAILY",
+		"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		"d 1 count 1000",
+		"w 1,5 count 1000",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,FR;COUNT=1000",
+	}
+	for _, repeat := range rules {
+		rule, err := recur.Parse(repeat)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, years := range []int{1, 10, 100} {
+			rule.Start = now.AddDate(-years, 0, 0)
+			b.Run(fmt.Sprintf("%s/%dy", repeat, years), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := rule.Next(now); err != nil && !errors.Is(err, recur.ErrSeriesEnded) {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/somepgs/go_final_project/pkg/recur"
+)
+
+func TestRecurString(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		want   string
+	}{
+		{"d 7", "d 7"},
+		{"y", "y"},
+		{"y 1", "y"},
+		{"w 7,1,5,1", "w 1,5,7"},
+		{"w 1 /1", "w 1"},
+		{"w 3  /2", "w 3 /2"},
+		{"m -1,15,1,-2 8,2", "m 1,15,-1,-2 2,8"},
+		{"mw -1:5,1:1", "mw 1:1,-1:5"},
+		{"wn 40,1,14,27 1", "wn 1,14,27,40"},
+		{"wn 53 5,1", "wn 53 1,5"},
+		{"q -1,1,45", "q 1,45,-1"},
+		{"cron 0 9 * * 1-5", "cron 0 9 * * 1-5"},
+		{"d 7 count 5 until 20240301", "d 7 until 20240301 count 5"},
+		{"m 15 shift next", "m 15 shift next"},
+		{"RRULE:FREQ=weekly;BYDAY=MO,FR;INTERVAL=2", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3 shift prev", "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3 shift prev"},
+	}
+	for _, v := range tbl {
+		rule, err := recur.Parse(v.repeat)
+		if !assert.NoError(t, err, v.repeat) {
+			continue
+		}
+		assert.Equal(t, v.want, rule.String(), v.repeat)
+
+		again, err := recur.Parse(rule.String())
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, rule.String(), again.String(), v.repeat)
+	}
+}
+
+func TestRecurErrors(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		field  recur.Field
+		value  string
+	}{
+		{"", recur.FieldRule, ""},
+		{"k 34", recur.FieldRule, "k"},
+		{"d 400", recur.FieldInterval, "400"},
+		{"w 1,8", recur.FieldWeekday, "8"},
+		{"w 1 /53", recur.FieldInterval, "/53"},
+		{"m 1,-3", recur.FieldDay, "-3"},
+		{"m 1 13", recur.FieldMonth, "13"},
+		{"mw 6:1", recur.FieldWeek, "6:1"},
+		{"wn 54", recur.FieldWeek, "54"},
+		{"wn 1 0", recur.FieldWeekday, "0"},
+		{"q 93", recur.FieldDay, "93"},
+		{"cron 0 9 * *", recur.FieldCron, "0 9 * *"},
+		{"RRULE:FREQ=HOURLY", recur.FieldRRule, "RRULE:FREQ=HOURLY"},
+		{"d 7 until 2024", recur.FieldUntil, "2024"},
+		{"d 7 count 2 count 3", recur.FieldCount, "2"},
+		{"m 15 shift later", recur.FieldShift, "later"},
+	}
+	for _, v := range tbl {
+		_, err := recur.Parse(v.repeat)
+		var rerr *recur.Error
+		if !assert.True(t, errors.As(err, &rerr), v.repeat) {
+			continue
+		}
+		assert.Equal(t, v.field, rerr.Field, v.repeat)
+		assert.Equal(t, v.value, rerr.Value, v.repeat)
+	}
+}
+
+func TestRecurNext(t *testing.T) {
+	rule, err := recur.Parse("w 1,5 /2 until 20240220")
+	assert.NoError(t, err)
+	rule.Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var got []string
+	after := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	for {
+		next, err := rule.Next(after)
+		if errors.Is(err, recur.ErrSeriesEnded) {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		got = append(got, next.Format("20060102"))
+		after = next
+	}
+	assert.Equal(t, []string{"20240115", "20240119", "20240129", "20240202", "20240212", "20240216"}, got)
+
+	rule, err = recur.Parse("h 2")
+	assert.NoError(t, err)
+	rule.Start = time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	next, err := rule.NextTime(time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 3, 13, 30, 0, 0, time.UTC), next)
+}
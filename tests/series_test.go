@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoneSeriesEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Принять таблетку",
+		repeat: "d 1 count 3",
+	})
+
+	var stored Task
+	err := db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stored.Remaining)
+
+	for i := 2; i > 0; i-- {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, i, stored.Remaining)
+	}
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	until := now.AddDate(0, 0, 2).Format(`20060102`)
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 2 until " + until,
+	})
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, until, stored.EndDate)
+
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	notFoundTask(t, id)
+}
+
+func TestAddSeriesStarted(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// Both syntaxes count the occurrences that have passed since the task date
+	now := time.Now()
+	for _, repeat := range []string{"d 7 count 3", "RRULE:FREQ=WEEKLY;COUNT=3"} {
+		id := addTask(t, task{
+			date:   now.AddDate(0, 0, -10).Format(`20060102`),
+			title:  "Сдать показания счётчиков",
+			repeat: repeat,
+		})
+		var stored Task
+		err := db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, 4).Format(`20060102`), stored.Date, repeat)
+		assert.Equal(t, 1, stored.Remaining, repeat)
+
+		ret, err := postJSON("api/task", map[string]any{
+			"date":   now.AddDate(0, 0, -30).Format(`20060102`),
+			"title":  "Сдать показания счётчиков",
+			"repeat": repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], repeat)
+	}
+}
+
+func TestAddSeriesStartedMinutely(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// The passed occurrences of a large series are walked once, not from the task date for every one of them
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   start.Format(`20060102`),
+		"time":   "00:00",
+		"title":  "Снять показания датчика",
+		"repeat": "cron * * * * * count 10000",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Less(t, time.Since(now), 5*time.Second)
+	id := fmt.Sprint(ret["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	passed := int(now.Truncate(time.Minute).Add(time.Minute).Sub(start) / time.Minute)
+	assert.InDelta(t, 10000-passed, stored.Remaining, 1)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   start.Format(`20060102`),
+		"time":   "00:00",
+		"title":  "Снять показания датчика",
+		"repeat": "cron * * * * * count 100000",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
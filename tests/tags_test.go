@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	add := func(title string, tags ...any) string {
+		ret, err := postJSON("api/task", map[string]any{"date": date, "title": title, "tags": tags}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"], title)
+		return fmt.Sprint(ret["id"])
+	}
+	tagsOf := func(id string) []any {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		tags, _ := m["tags"].([]any)
+		return tags
+	}
+	listed := func(tags string) []string {
+		body, err := requestJSON("api/tasks?tag="+url.QueryEscape(tags), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var ids []string
+		for _, task := range m["tasks"] {
+			ids = append(ids, fmt.Sprint(task["id"]))
+		}
+		return ids
+	}
+	call := func(path, method string) map[string]any {
+		ret, err := postJSON(path, nil, method)
+		assert.NoError(t, err)
+		return ret
+	}
+
+	home := add("Починить кран", "дом", " Дом ", "срочно")
+	work := add("Написать отчёт", "работа")
+	chores := add("Пропылесосить", "дом")
+	assert.Equal(t, []any{"дом", "срочно"}, tagsOf(home))
+	for _, tags := range [][]any{{""}, {"a,b"}} {
+		ret, err := postJSON("api/task", map[string]any{"date": date, "title": "Без тегов", "tags": tags}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], tags)
+	}
+
+	ids := listed("дом")
+	assert.Contains(t, ids, home)
+	assert.Contains(t, ids, chores)
+	assert.NotContains(t, ids, work)
+	ids = listed("дом,срочно")
+	assert.Contains(t, ids, home)
+	assert.NotContains(t, ids, chores)
+
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Contains(t, m["tags"], map[string]any{"name": "дом", "tasks": float64(2)})
+
+	// Rename, merge and delete
+	assert.Empty(t, call("api/tag?name=срочно&new="+url.QueryEscape("важно"), http.MethodPut))
+	assert.Equal(t, []any{"важно", "дом"}, tagsOf(home))
+	assert.NotEmpty(t, call("api/tag?name=важно&new="+url.QueryEscape("Дом"), http.MethodPut)["error"])
+	assert.NotEmpty(t, call("api/tag?name=нет&new="+url.QueryEscape("да"), http.MethodPut)["error"])
+	assert.Empty(t, call("api/tag/merge?from=работа&into="+url.QueryEscape("дом"), http.MethodPost))
+	assert.Equal(t, []any{"дом"}, tagsOf(work))
+	assert.Empty(t, call("api/tag?name=дом", http.MethodDelete))
+	assert.Equal(t, []any{"важно"}, tagsOf(home))
+	assert.Empty(t, tagsOf(chores))
+
+	// An update keeps the tags unless it lists them
+	ret, err := postJSON("api/task", map[string]any{"id": home, "date": date, "title": "Починить кран"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []any{"важно"}, tagsOf(home))
+	ret, err = postJSON("api/task", map[string]any{"id": home, "date": date, "title": "Починить кран", "tags": []any{}},
+		http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, tagsOf(home))
+
+	for _, id := range []string{home, work, chores} {
+		assert.Empty(t, call("api/task?id="+id, http.MethodDelete))
+	}
+	assert.Empty(t, call("api/tag?name=важно", http.MethodDelete))
+}
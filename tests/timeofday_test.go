@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateTime(t *testing.T) {
+	tbl := []struct {
+		now    string
+		date   string
+		time   string
+		tz     string
+		repeat string
+		want   string
+	}{
+		{"20240126 10:00", "20240126", "18:00", "", "d 1", "20240127 18:00"},
+		{"20240126 08:00", "20240120", "09:30", "", "d 1", "20240126 09:30"},
+		{"20240126 10:00", "20240120", "09:30", "", "d 1", "20240127 09:30"},
+		{"20240126", "20240120", "", "", "d 1", "20240127"},
+		{"20240126 12:30", "20240126", "09:00", "", "h 2", "20240126 13:00"},
+		{"20240126 10:00", "20240126", "09:00", "", "min 45", "20240126 10:30"},
+		{"20240126 12:00", "20240126", "09:00", "", "cron 30 18 * * 1-5", "20240126 18:30"},
+		{"20240126 17:50", "20240126", "09:00", "", "cron */15 9-17 * * *", "20240127 09:00"},
+		{"20240126 12:30", "20240126", "09:00", "", "h 2 count 3", "20240126 13:00"},
+		{"20240126 12:30", "20240126", "09:00", "", "h 2 count 2", ""},
+		{"20240126 12:00", "20240126", "09:00", "", "cron 30 18 * * 1-5 count 2", "20240126 18:30"},
+		{"20240126 12:00", "20240126", "09:00", "", "cron 30 18 * * 1-5 count 1", ""},
+		{"20240126 12:00", "20240126", "09:00", "", "cron * * * * * count 2000000000", ""},
+		{"20240131 00:00", "20240125", "00:00", "", "cron * * * * * count 8642", "20240131 00:01"},
+		{"20240131 00:00", "20240125", "00:00", "", "cron * * * * * count 8641", ""},
+		{"20240126 10:30", "20240126", "09:00", "Europe/Berlin", "h 1", "20240126 11:00"},
+		{"20240126 23:30", "20240126", "23:00", "", "h 1 until 20240126", ""},
+		{"20240126 10:00", "20240126", "09:00", "", "h 1 shift next", ""},
+		{"20240126 10:00", "20240126", "09:00", "", "h 0", ""},
+		{"20240126 10:00", "20240126", "09:00", "", "min 1441", ""},
+		{"20240126 10:00", "20240126", "", "", "h 2", ""},
+		{"20240126 10:00", "20240126", "9:00", "", "h 2", ""},
+		{"20240126 10:00", "20240126", "09:00", "Mars/Olympus", "h 2", ""},
+	}
+	for _, v := range tbl {
+		urlPath := "api/nextdate?now=" + url.QueryEscape(v.now) + "&date=" + v.date +
+			"&time=" + url.QueryEscape(v.time) + "&tz=" + url.QueryEscape(v.tz) + "&repeat=" + url.QueryEscape(v.repeat)
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if len(v.want) == 0 {
+			_, err = time.Parse("20060102", next[:min(len(next), 8)])
+			assert.Error(t, err, "%v: %s", v, next)
+			continue
+		}
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":     now.Format(`20060102`),
+		"time":     "25:00",
+		"title":    "Созвон",
+		"timezone": "Europe/Berlin",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+	ret, err = postJSON("api/task", map[string]any{
+		"date":     tomorrow,
+		"time":     "09:30",
+		"title":    "Созвон",
+		"timezone": "Europe/Berlin",
+		"repeat":   "h 6",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"])
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, stored.Date)
+	assert.Equal(t, "15:30", stored.Time)
+	assert.Equal(t, "Europe/Berlin", stored.TimeZone)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
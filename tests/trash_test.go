@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 2).Format(`20060102`)
+	call := func(path string, method string) map[string]any {
+		ret, err := postJSON(path, nil, method)
+		assert.NoError(t, err)
+		return ret
+	}
+	listed := func(path string) []string {
+		body, err := requestJSON(path, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		var ids []string
+		for _, task := range m["tasks"] {
+			ids = append(ids, fmt.Sprint(task["id"]))
+		}
+		return ids
+	}
+
+	id := addTask(t, task{date: date, title: "Корзина: купить хлеб", comment: "ржаной"})
+	assert.Empty(t, call("api/task?id="+id, http.MethodDelete))
+	notFoundTask(t, id)
+	assert.NotContains(t, listed("api/tasks"), id)
+	assert.NotContains(t, listed("api/tasks?search="+url.QueryEscape("ржаной")), id)
+	assert.NotEmpty(t, call("api/task?id="+id, http.MethodDelete)["error"])
+	assert.NotEmpty(t, call("api/task/done?id="+id, http.MethodPost)["error"])
+
+	body, err := requestJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["tasks"])
+	if len(m["tasks"]) > 0 {
+		trashed := m["tasks"][0]
+		assert.Equal(t, id, trashed["id"])
+		assert.Equal(t, "Корзина: купить хлеб", trashed["title"])
+		at, err := time.Parse(time.RFC3339, fmt.Sprint(trashed["deleted_at"]))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), at, time.Minute)
+	}
+
+	// A restored task is back as it was
+	assert.Empty(t, call("api/trash/restore?id="+id, http.MethodPost))
+	assert.NotEmpty(t, call("api/trash/restore?id="+id, http.MethodPost)["error"])
+	assert.NotContains(t, listed("api/trash"), id)
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var restored map[string]any
+	assert.NoError(t, json.Unmarshal(body, &restored))
+	assert.Equal(t, date, restored["date"])
+	assert.Equal(t, "ржаной", restored["comment"])
+	assert.Nil(t, restored["deleted_at"])
+
+	// Only tasks in the trash can be purged
+	assert.NotEmpty(t, call("api/trash?id="+id, http.MethodDelete)["error"])
+	assert.Empty(t, call("api/task?id="+id, http.MethodDelete))
+	assert.Equal(t, map[string]any{"purged": float64(1)}, call("api/trash?id="+id, http.MethodDelete))
+	assert.NotEmpty(t, call("api/trash/restore?id="+id, http.MethodPost)["error"])
+	var count int
+	assert.NoError(t, db.Get(&count, `SELECT count(*) FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, 0, count)
+
+	// A task that follows a trashed one can still be edited, keeping its dependency for a restore
+	first := addTask(t, task{date: date, title: "Корзина: заказать краску"})
+	ret, err := postJSON("api/task", map[string]any{"date": date, "title": "Корзина: покрасить забор",
+		"depends": "after " + first + " +2d"}, http.MethodPost)
+	assert.NoError(t, err)
+	second := fmt.Sprint(ret["id"])
+	assert.Empty(t, call("api/task?id="+first, http.MethodDelete))
+	for _, depends := range []string{"", "after " + first + " +2d"} {
+		ret, err = postJSON("api/task", map[string]any{"id": second, "date": date, "title": "Корзина: покрасить забор",
+			"comment": "в два слоя", "depends": depends}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret, depends)
+	}
+	ret, err = postJSON("api/task", map[string]any{"id": second, "date": date, "title": "Корзина: покрасить забор",
+		"depends": "after " + first + " +3d"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Empty(t, call("api/trash/restore?id="+first, http.MethodPost))
+	body, err = requestJSON("api/task?id="+second, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var follower map[string]any
+	assert.NoError(t, json.Unmarshal(body, &follower))
+	assert.Equal(t, "after "+first+" +2d", follower["depends"])
+	assert.Equal(t, "в два слоя", follower["comment"])
+
+	// Emptying the trash purges every deleted task
+	first = addTask(t, task{date: date, title: "Корзина: первая"})
+	second = addTask(t, task{date: date, title: "Корзина: вторая"})
+	assert.Empty(t, call("api/task?id="+first, http.MethodDelete))
+	assert.Empty(t, call("api/task?id="+second, http.MethodDelete))
+	ret = call("api/trash", http.MethodDelete)
+	assert.GreaterOrEqual(t, ret["purged"], float64(2))
+	assert.Empty(t, listed("api/trash"))
+}